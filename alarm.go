@@ -0,0 +1,428 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Alarm is a backend of the alarm triggered when a timer is over.
+type Alarm interface {
+	// Fire triggers the alarm and returns when it is over. It stops
+	// early when ack is closed. An error tells that the alarm could
+	// not reach the user.
+	Fire(n notice, ack <-chan struct{}) error
+}
+
+// notice is what the alarm tells the user.
+type notice struct {
+	summary, body string
+	// last is true for the alarm when the whole timer is over.
+	last bool
+	// style is the appearance of desktop notification.
+	style notificationStyle
+	// actions receives the key of notificationActions chosen by the
+	// user if it is not nil.
+	actions chan<- string
+	// chime is true for the chime of --every, which doesn't replace the
+	// progress notification.
+	chime bool
+}
+
+// urgencies are the levels of urgency in desktop notifications.
+var urgencies = map[string]byte{"low": 0, "normal": 1, "critical": 2}
+
+// neverExpire is the expiry of the notification which stays until the
+// user closes it.
+const neverExpire time.Duration = -1
+
+// notificationStyle is the appearance of desktop notification given by
+// the flags or the notification table of a recipe step. The zero value
+// leaves everything to the server.
+type notificationStyle struct {
+	// urgency is low, normal or critical. Empty means normal.
+	urgency string
+	// expire is neverExpire, or zero for the default of the server.
+	expire time.Duration
+	// icon is the name or the absolute path of the image.
+	icon                 string
+	category             string
+	soundFile, soundName string
+	// resident keeps the notification after an action is invoked, and
+	// transient keeps it out of the history.
+	resident, transient bool
+}
+
+// validate checks urgency and the conflicting hints.
+func (s notificationStyle) validate() error {
+	if _, ok := urgencies[s.urgency]; !ok && s.urgency != "" {
+		return fmt.Errorf("Unknown urgency: %s", s.urgency)
+	}
+	if s.resident && s.transient {
+		return errors.New("resident and transient cannot be used together")
+	}
+	return nil
+}
+
+// merge returns s overridden by the fields set in o.
+func (s notificationStyle) merge(o notificationStyle) notificationStyle {
+	for _, f := range []struct{ dst, src *string }{
+		{&s.urgency, &o.urgency},
+		{&s.icon, &o.icon},
+		{&s.category, &o.category},
+		{&s.soundFile, &o.soundFile},
+		{&s.soundName, &o.soundName},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+	if o.expire != 0 {
+		s.expire = o.expire
+	}
+	if o.resident || o.transient {
+		s.resident, s.transient = o.resident, o.transient
+	}
+	return s
+}
+
+// hints returns the hints of Notify.
+func (s notificationStyle) hints() map[string]interface{} {
+	urgency, ok := urgencies[s.urgency]
+	if !ok {
+		urgency = urgencies["normal"]
+	}
+	hints := map[string]interface{}{"urgency": urgency}
+	for key, v := range map[string]string{
+		"image-path": s.icon,
+		"category":   s.category,
+		"sound-file": s.soundFile,
+		"sound-name": s.soundName,
+	} {
+		if v != "" {
+			hints[key] = v
+		}
+	}
+	if s.resident {
+		hints["resident"] = true
+	}
+	if s.transient {
+		hints["transient"] = true
+	}
+	return hints
+}
+
+// timeout returns the expiry in milliseconds for Notify, where -1 is the
+// default of the server and 0 never expires.
+func (s notificationStyle) timeout() int32 {
+	switch {
+	case s.expire == neverExpire:
+		return 0
+	case s.expire > 0:
+		return int32(s.expire / time.Millisecond)
+	}
+	return -1
+}
+
+// parseExpire parses the expiry of the notification: TIME, never or
+// default.
+func parseExpire(s string) (time.Duration, error) {
+	switch s {
+	case "never":
+		return neverExpire, nil
+	case "default":
+		return 0, nil
+	}
+	if d, err := parseInterval(s); err == nil && d >= time.Millisecond {
+		return d, nil
+	}
+	return 0, fmt.Errorf("Invalid expiry: %s", s)
+}
+
+// iconPath makes the path of an existing image file absolute, since the
+// server doesn't know the working directory. Others are icon names.
+func iconPath(icon string) string {
+	if _, err := os.Stat(icon); err != nil {
+		return icon
+	}
+	if abs, err := filepath.Abs(icon); err == nil {
+		return abs
+	}
+	return icon
+}
+
+// errNotTerminal is returned by the alarms which need a terminal.
+var errNotTerminal = errors.New("not a terminal")
+
+// alarmBackends are the constructors of the alarms selected by --alarm.
+var alarmBackends = map[string]func(cli *CLI) Alarm{
+	"dbus": func(cli *CLI) Alarm {
+		return dbusAlarm{bus: cli.bus, err: cli.busErr}
+	},
+	"osc": func(cli *CLI) Alarm {
+		kind := cli.notifier
+		if !strings.HasPrefix(kind, "osc") {
+			kind = detectOSC()
+		}
+		return oscAlarm{w: cli.escapes(), tty: cli.tty, kind: kind}
+	},
+	"flash": func(cli *CLI) Alarm {
+		return flashAlarm{w: cli.escapes(), tty: cli.tty, flash: cli.flash}
+	},
+	"bell": func(cli *CLI) Alarm {
+		bell := cli.bell
+		if bell == nil {
+			bell = &bellRinger{w: cli.escapes(), interval: defaultBellInterval}
+		}
+		return bellAlarm{bell: bell, tty: cli.tty}
+	},
+	"command": func(cli *CLI) Alarm {
+		return commandAlarm{command: cli.command, w: cli.errStream}
+	},
+	"print": func(cli *CLI) Alarm {
+		return printAlarm{w: cli.outStream}
+	},
+	"sound": func(cli *CLI) Alarm {
+		return soundAlarm{sound: cli.sound, w: cli.errStream}
+	},
+	"wall": func(cli *CLI) Alarm {
+		return wallAlarm{}
+	},
+}
+
+// fallbackAlarms are fired when none of the selected alarms succeeded.
+var fallbackAlarms = []string{"bell", "print"}
+
+// alarmNames returns the names of alarmBackends in order.
+func alarmNames() []string {
+	var names []string
+	for name := range alarmBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseAlarms splits comma separated names of alarms.
+func parseAlarms(s string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := alarmBackends[name]; !ok {
+			return nil, fmt.Errorf("Unknown alarm: %s", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// alarmList is the value of repeatable --alarm flags.
+type alarmList []string
+
+func (l *alarmList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *alarmList) Set(s string) error {
+	names, err := parseAlarms(s)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, names...)
+	return nil
+}
+
+// usesAlarm reports whether the alarm of name can be fired for steps
+// when alarms are the default.
+func usesAlarm(name string, alarms []string, steps []step) bool {
+	if isOneOf(name, alarms) {
+		return true
+	}
+	for _, st := range steps {
+		if isOneOf(name, st.alarms) || usesAlarm(name, nil, st.parallel) {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationActions are the keys and the labels of the buttons on the
+// notification of the last alarm.
+var notificationActions = []string{"snooze", "Snooze", "extend", "+5 min", "dismiss", "Dismiss"}
+
+// extension is the time added to the timer by the extend action.
+const extension = 5 * time.Minute
+
+// actionTimeout is how long the notification with actions waits for the
+// user unless the expiry is given.
+const actionTimeout = time.Minute
+
+// dbusAlarm shows desktop notification with the notification server on
+// D-Bus. If notice has actions and the server supports them, it waits
+// for the action until the notification is closed or acknowledged.
+type dbusAlarm struct {
+	bus *notificationsClient
+	// err tells why bus is not available.
+	err error
+}
+
+func (a dbusAlarm) Fire(n notice, ack <-chan struct{}) error {
+	if a.bus == nil {
+		return fmt.Errorf("notification server is not available: %v", a.err)
+	}
+	hints := n.style.hints()
+	body := a.bus.text(n.body)
+	var replaces uint32
+	if !n.chime {
+		replaces = a.bus.takeOver()
+	}
+	if n.actions == nil || !a.bus.supports("actions") {
+		_, err := a.bus.notify(replaces, "appointment-soon", n.summary, body, nil, hints, n.style.timeout())
+		return err
+	}
+
+	// The notification waits for the action until it expires.
+	timeout := actionTimeout
+	switch {
+	case n.style.expire == neverExpire:
+		timeout = 0
+	case n.style.expire > 0:
+		timeout = n.style.expire
+	}
+	id, err := a.bus.notify(replaces, "appointment-soon", n.summary, body, notificationActions, hints, int32(timeout/time.Millisecond))
+	if err != nil {
+		return err
+	}
+	key := a.bus.actionInvoked(id, ack, timeout)
+	// Servers may keep the notification after the action or ignore
+	// the timeout.
+	a.bus.closeNotification(id)
+	if key != "" {
+		select {
+		case n.actions <- key:
+		default:
+		}
+	}
+	return nil
+}
+
+// oscAlarm asks the terminal to show notification with kind of escape
+// sequence.
+type oscAlarm struct {
+	w    io.Writer
+	tty  bool
+	kind string
+}
+
+func (a oscAlarm) Fire(n notice, ack <-chan struct{}) error {
+	if !a.tty {
+		return errNotTerminal
+	}
+	_, err := fmt.Fprint(a.w, oscNotification(a.kind, n.summary, n.body))
+	return err
+}
+
+// flashAlarm flashes the screen. With --flash-until-ack, the last alarm
+// flashes until it is acknowledged.
+type flashAlarm struct {
+	w     io.Writer
+	tty   bool
+	flash flashConfig
+}
+
+func (a flashAlarm) Fire(n notice, ack <-chan struct{}) error {
+	if !a.tty {
+		return errNotTerminal
+	}
+	a.flash.untilAck = a.flash.untilAck && n.last
+	flashScreen(a.w, a.flash, ack)
+	return nil
+}
+
+// bellAlarm rings the terminal bell.
+type bellAlarm struct {
+	bell *bellRinger
+	tty  bool
+}
+
+func (a bellAlarm) Fire(n notice, ack <-chan struct{}) error {
+	if !a.tty {
+		return errNotTerminal
+	}
+	// The bell dropped by the rate limit has just rung.
+	if err := a.bell.ring(); err != errBellDropped {
+		return err
+	}
+	return nil
+}
+
+// commandAlarm runs the shell command given by --alarm-command with the
+// notification in TIME_TO_GO_SUMMARY and TIME_TO_GO_BODY. The command is
+// killed when the alarm is acknowledged.
+type commandAlarm struct {
+	command string
+	// w receives the output of the command.
+	w io.Writer
+}
+
+func (a commandAlarm) Fire(n notice, ack <-chan struct{}) error {
+	if a.command == "" {
+		return errors.New("no command is given")
+	}
+	cmd := exec.Command("sh", "-c", a.command)
+	cmd.Env = append(os.Environ(), "TIME_TO_GO_SUMMARY="+n.summary, "TIME_TO_GO_BODY="+n.body)
+	cmd.Stdout, cmd.Stderr = a.w, a.w
+	return runUntilAck(cmd, ack)
+}
+
+// runUntilAck runs cmd and waits for it. The command and its children are
+// killed when ack is closed.
+func runUntilAck(cmd *exec.Cmd, ack <-chan struct{}) error {
+	if err := startGroup(cmd); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ack:
+		killGroup(cmd)
+		<-done
+		return nil
+	}
+}
+
+// wallAlarm writes the notification to the terminals of all users with
+// wall(1), which reaches the user logged in elsewhere.
+type wallAlarm struct{}
+
+func (a wallAlarm) Fire(n notice, ack <-chan struct{}) error {
+	cmd := exec.Command("wall")
+	cmd.Stdin = strings.NewReader(n.summary + ": " + n.body + "\n")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// printAlarm writes the notification as a line, which works anywhere.
+type printAlarm struct {
+	w io.Writer
+}
+
+func (a printAlarm) Fire(n notice, ack <-chan struct{}) error {
+	_, err := fmt.Fprintf(a.w, "%s: %s\n", n.summary, n.body)
+	return err
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAlarms(t *testing.T) {
+	var l alarmList
+	for _, s := range []string{"flash, bell", "print"} {
+		if err := l.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := (alarmList{"flash", "bell", "print"}); !reflect.DeepEqual(l, expected) {
+		t.Errorf("expected %q to eq %q", l, expected)
+	}
+	if err := l.Set("siren"); err == nil {
+		t.Errorf("expected error for unknown alarm")
+	}
+
+	names, err := decodeAlarms([]interface{}{"osc", "command"})
+	if err != nil || !reflect.DeepEqual(names, []string{"osc", "command"}) {
+		t.Errorf("expected %q to eq %q (%v)", names, []string{"osc", "command"}, err)
+	}
+}
+
+func TestNotificationStyle(t *testing.T) {
+	base := notificationStyle{urgency: "normal", category: "im", transient: true}
+	doc, err := parseYAML([]byte("urgency: critical\nexpire: never\nsound-name: alarm-clock-elapsed\nresident: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := decodeNotificationStyle(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := base.merge(o)
+	expected := map[string]interface{}{"urgency": byte(2), "category": "im", "sound-name": "alarm-clock-elapsed", "resident": true}
+	if hints := s.hints(); !reflect.DeepEqual(hints, expected) {
+		t.Errorf("expected %v to eq %v", hints, expected)
+	}
+	if s.timeout() != 0 || base.timeout() != -1 {
+		t.Errorf("expected %d and %d to eq 0 and -1", s.timeout(), base.timeout())
+	}
+
+	if d, err := parseExpire("30s"); err != nil || d != 30*time.Second {
+		t.Errorf("expected %v to eq %v (%v)", d, 30*time.Second, err)
+	}
+	for _, arg := range []string{"--expire soon", "--urgency loud", "--resident --transient"} {
+		var errs bytes.Buffer
+		cli := &CLI{outStream: &bytes.Buffer{}, errStream: &errs}
+		if status := cli.Run(strings.Split("time-to-go "+arg+" 1s", " ")); status != ExitCodeError {
+			t.Errorf("expected %d to eq %d for %s", status, ExitCodeError, arg)
+		}
+	}
+}
+
+func TestCLI_alarm(t *testing.T) {
+	var out, errs, events bytes.Buffer
+	cli := &CLI{outStream: &out, errStream: &errs}
+	opt := &options{alarms: []string{"flash", "bell"}, events: newEventWriter(&events)}
+	s := &status{step: step{duration: time.Minute, label: "tea"}}
+
+	// The alarms need a terminal, so it falls back to print.
+	cli.alarm(opt, s, notice{summary: "time-to-go", body: "tea is over."}, nil)
+	if expected := "time-to-go: tea is over.\n"; out.String() != expected {
+		t.Errorf("expected %q to eq %q", out.String(), expected)
+	}
+	if expected := "Alarm failed: flash (not a terminal), bell (not a terminal). Fired: print\n"; errs.String() != expected {
+		t.Errorf("expected %q to eq %q", errs.String(), expected)
+	}
+	var ev event
+	if err := json.Unmarshal(events.Bytes(), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Event != "alarm" || !reflect.DeepEqual(ev.Alarms, []string{"print"}) {
+		t.Errorf("unexpected event %+v", ev)
+	}
+
+	// The alarms of the step take precedence.
+	out.Reset()
+	errs.Reset()
+	cli.command = `printf '%s|%s' "$TIME_TO_GO_SUMMARY" "$TIME_TO_GO_BODY"`
+	s.step.alarms = []string{"command"}
+	cli.alarm(opt, s, notice{summary: "time-to-go", body: "tea is over."}, nil)
+	if expected := "time-to-go|tea is over."; errs.String() != expected || out.Len() != 0 {
+		t.Errorf("expected %q to eq %q", errs.String(), expected)
+	}
+}
+
+func TestCLI_ringChime(t *testing.T) {
+	// Without a terminal, the chime is printed instead of escape sequences.
+	for _, kind := range []string{"bell", "flash"} {
+		var out bytes.Buffer
+		cli := &CLI{outStream: &out}
+		cli.ringChime(kind, time.Minute, nil)
+		if expected := "time-to-go: 1min0s elapsed\n"; out.String() != expected {
+			t.Errorf("%s: expected %q to eq %q", kind, out.String(), expected)
+		}
+	}
+}
+
+func TestCommandAlarm_ack(t *testing.T) {
+	ack := make(chan struct{})
+	close(ack)
+	start := time.Now()
+	a := commandAlarm{command: "sleep 10", w: &bytes.Buffer{}}
+	if err := a.Fire(notice{}, ack); err != nil {
+		t.Errorf("expected %v to be nil", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected %v to be killed", d)
+	}
+	if !strings.Contains((commandAlarm{}).Fire(notice{}, nil).Error(), "no command") {
+		t.Errorf("expected error without command")
+	}
+}
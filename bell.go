@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultBellInterval is the default of --bell-interval.
+const defaultBellInterval = 500 * time.Millisecond
+
+// tickSound is played by --bell-tick with the sound alarm, which is
+// short not to overlap the next tick.
+var tickSound = synthesize([]note{{1760, 40 * time.Millisecond}})
+
+// errBellDropped is returned for the bell rung within the interval from
+// the last one.
+var errBellDropped = errors.New("dropped within --bell-interval")
+
+// bellRinger rings the terminal bell at most once in interval, since
+// some terminals queue bells and ring them one after another long after
+// the alarm.
+type bellRinger struct {
+	w        io.Writer
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// ring writes BEL to w, which passes it through the multiplexers. The
+// volume is left as it is, since terminals don't report the volume to be
+// restored after the bell. The bell within interval from the last one is
+// dropped with errBellDropped.
+func (b *bellRinger) ring() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if now.Sub(b.last) < b.interval {
+		return errBellDropped
+	}
+	b.last = now
+	_, err := fmt.Fprint(b.w, "\a")
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBellRinger(t *testing.T) {
+	var out bytes.Buffer
+	b := &bellRinger{w: &out, interval: 50 * time.Millisecond}
+	if err := b.ring(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := b.ring(); err != errBellDropped {
+			t.Errorf("expected %v to eq %v", err, errBellDropped)
+		}
+	}
+	if expected := "\a"; out.String() != expected {
+		t.Errorf("expected %q to eq %q", out.String(), expected)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := b.ring(); err != nil {
+		t.Errorf("expected %v to be rung after the interval", err)
+	}
+
+	// The bell passes through tmux.
+	b.w, b.last = passthrough{w: &out, layers: []string{"tmux"}}, time.Time{}
+	out.Reset()
+	b.ring()
+	if expected := "\x1bPtmux;\a\x1b\\"; out.String() != expected {
+		t.Errorf("expected %q to eq %q", out.String(), expected)
+	}
+}
+
+func TestBellAlarm_dropped(t *testing.T) {
+	// The bell rung just before by others doesn't fail the alarm.
+	var out bytes.Buffer
+	b := &bellRinger{w: &out, interval: time.Minute}
+	b.ring()
+	if err := (bellAlarm{bell: b, tty: true}).Fire(notice{}, nil); err != nil {
+		t.Errorf("expected %v to be nil", err)
+	}
+	if expected := "\a"; out.String() != expected {
+		t.Errorf("expected %q to eq %q", out.String(), expected)
+	}
+}
+
+func TestCLI_tickBell(t *testing.T) {
+	var out bytes.Buffer
+	var g sync.WaitGroup
+	cli := &CLI{outStream: &out, tty: true, bell: &bellRinger{w: &out}}
+	cli.tickBell(&options{}, &g, nil)
+	if expected := "\a"; out.String() != expected {
+		t.Errorf("expected %q to eq %q", out.String(), expected)
+	}
+
+	// The sound alarm plays the tick instead.
+	out.Reset()
+	dir, err := ioutil.TempDir("", "time-to-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := filepath.Join(dir, "sink")
+	cli.sound = soundConfig{sink: sink}
+	cli.tickBell(&options{alarms: []string{"sound"}}, &g, nil)
+	g.Wait()
+	data, err := ioutil.ReadFile(sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, tickSound.raw()) || out.Len() != 0 {
+		t.Errorf("expected the tick to be played without the bell")
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// glyphs are the block digits used by bigDisplay. Each of them is five
+// pixels tall.
+var glyphs = map[rune][5]string{
+	'0': {"###", "# #", "# #", "# #", "###"},
+	'1': {" # ", "## ", " # ", " # ", "###"},
+	'2': {"###", "  #", "###", "#  ", "###"},
+	'3': {"###", "  #", "###", "  #", "###"},
+	'4': {"# #", "# #", "###", "  #", "  #"},
+	'5': {"###", "#  ", "###", "  #", "###"},
+	'6': {"###", "#  ", "###", "# #", "###"},
+	'7': {"###", "  #", "  #", "  #", "  #"},
+	'8': {"###", "# #", "###", "# #", "###"},
+	'9': {"###", "# #", "###", "  #", "###"},
+	':': {" ", "#", " ", "#", " "},
+}
+
+// threshold is the remained time where the color of bigDisplay changes.
+// It is either a ratio to the duration of the step or an absolute time.
+type threshold struct {
+	ratio    float64
+	duration time.Duration
+}
+
+// reached reports whether rem seconds of total is at or below t.
+func (t threshold) reached(rem int, total time.Duration) bool {
+	r := time.Duration(rem) * time.Second
+	if t.ratio > 0 {
+		return float64(r) <= float64(total)*t.ratio
+	}
+	return r <= t.duration
+}
+
+// thresholds are the remained time to turn yellow and red.
+type thresholds [2]threshold
+
+// level returns 0, 1 or 2 when rem seconds of total is green, yellow or
+// red respectively.
+func (ts thresholds) level(rem int, total time.Duration) int {
+	switch {
+	case ts[1].reached(rem, total):
+		return 2
+	case ts[0].reached(rem, total):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// parseThresholds converts "YELLOW,RED" such as "50%,20%" or "5min,1min"
+// to thresholds.
+func parseThresholds(s string) (thresholds, error) {
+	var ts thresholds
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return ts, fmt.Errorf("Invalid thresholds: %s", s)
+	}
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if strings.HasSuffix(p, "%") {
+			var pct float64
+			if _, err := fmt.Sscanf(p, "%g%%", &pct); err != nil || pct <= 0 || pct > 100 {
+				return ts, fmt.Errorf("Invalid thresholds: %s", s)
+			}
+			ts[i].ratio = pct / 100
+			continue
+		}
+		d, err := parseInterval(p)
+		if err != nil || d <= 0 {
+			return ts, fmt.Errorf("Invalid thresholds: %s", s)
+		}
+		ts[i].duration = d
+	}
+	return ts, nil
+}
+
+// bigDisplay renders remained time with large block digits on the
+// alternate screen buffer while a step is running. The color changes from
+// green to yellow and red at the thresholds.
+type bigDisplay struct {
+	w          io.Writer
+	f          *os.File
+	thresholds thresholds
+	color      bool
+	// ascii draws the digits with "#" unless the locale is UTF-8.
+	ascii bool
+
+	mu   sync.Mutex
+	last *status
+	// alt is true while the alternate screen buffer is shown.
+	alt    bool
+	winch  chan os.Signal
+	closed bool
+}
+
+// newBigDisplay starts watching the resize of the terminal. The
+// alternate screen buffer is shown when a step begins, after its
+// instructions are written to the normal one.
+func newBigDisplay(w io.Writer, thresholds thresholds, color bool) *bigDisplay {
+	d := &bigDisplay{w: w, thresholds: thresholds, color: color, ascii: !isUTF8Locale(), winch: make(chan os.Signal, 1)}
+	d.f, _ = w.(*os.File)
+	notifyResize(d.winch)
+	go func() {
+		for range d.winch {
+			d.mu.Lock()
+			if d.last != nil && d.alt {
+				d.render(d.last)
+			}
+			d.mu.Unlock()
+		}
+	}()
+	return d
+}
+
+// enter switches to the alternate screen buffer unless it is shown.
+func (d *bigDisplay) enter() {
+	if d.alt {
+		return
+	}
+	d.alt = true
+	// alternate screen buffer and hidden cursor
+	fmt.Fprint(d.w, "\x1b[?1049h\x1b[?25l")
+}
+
+// leave switches back to the normal screen buffer if the alternate one
+// is shown.
+func (d *bigDisplay) leave() {
+	if !d.alt {
+		return
+	}
+	d.alt = false
+	// normal screen buffer, visible cursor and default color
+	fmt.Fprint(d.w, "\x1b[0m\x1b[?25h\x1b[?1049l")
+}
+
+func (d *bigDisplay) begin(s *status) {
+	d.mu.Lock()
+	if !d.closed {
+		d.enter()
+	}
+	d.mu.Unlock()
+	d.update(s)
+}
+
+func (d *bigDisplay) update(s *status) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed || !d.alt {
+		return
+	}
+	c := *s
+	d.last = &c
+	d.render(&c)
+}
+
+// end leaves the alternate screen buffer so that the output of the
+// alarms remains in the scrollback of the normal one.
+func (d *bigDisplay) end(s *status) {
+	d.update(s)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.leave()
+}
+
+func (d *bigDisplay) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	d.closed = true
+	signal.Stop(d.winch)
+	close(d.winch)
+	d.leave()
+}
+
+// paint returns the escape sequence of the color for s.
+func (d *bigDisplay) paint(s *status) string {
+	if !d.color {
+		return ""
+	}
+	switch d.thresholds.level(s.remains, s.step.duration) {
+	case 2:
+		return "\x1b[31;1m"
+	case 1:
+		return "\x1b[33;1m"
+	default:
+		return "\x1b[32;1m"
+	}
+}
+
+// render draws s centered, scaling the digits to the terminal size.
+func (d *bigDisplay) render(s *status) {
+	cols, rows := 80, 24
+	if d.f != nil {
+		if c, r, ok := terminalSize(d.f); ok {
+			cols, rows = c, r
+		}
+	}
+
+	text := clock(s.remains)
+	caption := strings.TrimSpace(s.step.label + s.progress() + s.pausedMark())
+
+	// Each pixel is twice as wide as it is tall to look square.
+	width := 0
+	for _, r := range text {
+		width += len(glyphs[r][0]) + 1
+	}
+	width--
+	scale := cols / (width * 2)
+	if n := (rows - 2) / 5; n < scale {
+		scale = n
+	}
+
+	pixel := "█"
+	if d.ascii {
+		pixel = "#"
+	}
+	var lines []string
+	if scale < 1 {
+		lines = []string{text}
+	} else {
+		for y := 0; y < 5; y++ {
+			var b strings.Builder
+			for i, r := range text {
+				if i > 0 {
+					b.WriteString(strings.Repeat(" ", 2*scale))
+				}
+				for _, p := range glyphs[r][y] {
+					if p == '#' {
+						b.WriteString(strings.Repeat(pixel, 2*scale))
+					} else {
+						b.WriteString(strings.Repeat(" ", 2*scale))
+					}
+				}
+			}
+			for i := 0; i < scale; i++ {
+				lines = append(lines, b.String())
+			}
+		}
+	}
+
+	// Overwrite each line instead of clearing the screen to avoid flicker.
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	top := (rows - len(lines) - 2) / 2
+	if top < 0 {
+		top = 0
+	}
+	b.WriteString(strings.Repeat("\x1b[K\r\n", top))
+	b.WriteString(d.paint(s))
+	for _, l := range lines {
+		b.WriteString(center(l, cols))
+		b.WriteString("\x1b[K\r\n")
+	}
+	b.WriteString("\x1b[0m\x1b[K\r\n")
+	b.WriteString(center(caption, cols))
+	b.WriteString("\x1b[K\x1b[J")
+	fmt.Fprint(d.w, b.String())
+}
+
+// center pads s with spaces to be centered in cols columns.
+func center(s string, cols int) string {
+	n := len([]rune(s))
+	if n >= cols {
+		return s
+	}
+	return strings.Repeat(" ", (cols-n)/2) + s
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseThresholds(t *testing.T) {
+	ts, err := parseThresholds("50%,1min")
+	if err != nil {
+		t.Fatalf("parseThresholds returned error: %v", err)
+	}
+	total := 10 * time.Minute
+	cases := []struct {
+		rem         int
+		yellow, red bool
+	}{
+		{301, false, false},
+		{300, true, false},
+		{60, true, true},
+	}
+	for _, c := range cases {
+		if ts[0].reached(c.rem, total) != c.yellow || ts[1].reached(c.rem, total) != c.red {
+			t.Errorf("unexpected thresholds at %d seconds", c.rem)
+		}
+	}
+
+	for _, s := range []string{"50%", "0%,10%", "soon,1min"} {
+		if _, err := parseThresholds(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestBigDisplay_ascii(t *testing.T) {
+	var b bytes.Buffer
+	d := &bigDisplay{w: &b, ascii: true}
+	d.render(&status{step: step{duration: time.Minute}, remains: 42})
+	if s := b.String(); strings.Contains(s, "█") || !strings.Contains(s, "##") {
+		t.Errorf("expected %q to be drawn with ASCII", s)
+	}
+}
+
+func TestBigDisplay_end(t *testing.T) {
+	// The instructions before the first step are written to the normal
+	// screen.
+	var b bytes.Buffer
+	d := newBigDisplay(&b, thresholds{}, false)
+	if b.Len() != 0 {
+		t.Errorf("expected %q to be empty before the step", b.String())
+	}
+	d.begin(&status{step: step{duration: time.Minute}, remains: 60})
+	d.end(&status{step: step{duration: time.Minute}})
+	// The alarms after the step are written to the normal screen.
+	if s := b.String(); !strings.HasPrefix(s, "\x1b[?1049h") || !strings.HasSuffix(s, "\x1b[?1049l") {
+		t.Errorf("expected %q to leave the alternate screen", s)
+	}
+	b.Reset()
+	d.update(&status{step: step{duration: time.Minute}})
+	d.close()
+	if b.Len() != 0 {
+		t.Errorf("expected %q to be empty on the normal screen", b.String())
+	}
+
+	// The next step is drawn on the alternate screen again.
+	d = &bigDisplay{w: &b, winch: make(chan os.Signal, 1)}
+	d.end(&status{step: step{duration: time.Minute}})
+	d.begin(&status{step: step{duration: time.Minute}, remains: 60})
+	if s := b.String(); !strings.HasPrefix(s, "\x1b[?1049h") {
+		t.Errorf("expected %q to enter the alternate screen", s)
+	}
+}
@@ -1,16 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
+	"text/template"
 	"time"
-
-	"github.com/mqu/go-notify"
 )
 
 // Exit codes are int values that represent an exit code for a particular error.
@@ -21,9 +24,78 @@ const (
 
 // CLI is the command line object
 type CLI struct {
+	// inStream is the stdin to read confirmation of recipe steps.
+	inStream io.Reader
 	// outStream and errStream are the stdout and stderr
 	// to write message from the CLI.
 	outStream, errStream io.Writer
+
+	// tty is true if outStream is a terminal which accepts ANSI escape
+	// sequences. color is true if messages on errStream are colored.
+	tty, color bool
+	// multiplexers are the terminal multiplexers between time-to-go and
+	// the terminal, from the innermost one.
+	multiplexers []string
+	// notifier is the way to show the notification of the alarm.
+	notifier string
+	// flash is the pattern of flashScreen for the alarm.
+	flash flashConfig
+	// command is the shell command run by the command alarm.
+	command string
+	// sound is played by the sound alarm.
+	sound soundConfig
+	// bell rings the terminal bell of the alarms, the chime and the tick.
+	bell *bellRinger
+	// notification is the appearance of desktop notification, which
+	// steps can override.
+	notification notificationStyle
+	// bus is the connection to the notification server on D-Bus. busErr
+	// tells why it is nil.
+	bus    *notificationsClient
+	busErr error
+}
+
+// options holds the behaviour of the countdown given by command line flags.
+type options struct {
+	simple   bool
+	interval int
+	chime    string
+	// message is the template of notification body given by --message.
+	message    *template.Template
+	display    display
+	thresholds thresholds
+	// refresh is the interval of updating remained time in seconds.
+	refresh int
+	// events is nil unless --output json is given.
+	events *eventWriter
+	// alarms are the names of alarmBackends fired when a timer is over
+	// unless the timer selects its own.
+	alarms []string
+	// snooze is the time to snooze the alarm from the notification.
+	snooze time.Duration
+	// escalation repeats the last alarm until it is acknowledged.
+	escalation escalation
+	// tick is the remained seconds from which the bell ticks.
+	tick int
+}
+
+// flashes reports whether the screen flashes by the alarms, the
+// escalation or the chime of opt, or by the alarms of steps.
+func (opt *options) flashes(steps []step) bool {
+	return opt.interval > 0 && opt.chime == "flash" || usesAlarm("flash", append(opt.alarms, opt.escalation.alarms...), steps)
+}
+
+// body returns the notification body for st started at start.
+// def is used if --message is not given.
+func (opt *options) body(st step, start time.Time, def string) string {
+	if opt.message == nil {
+		return def
+	}
+	var b bytes.Buffer
+	if err := opt.message.Execute(&b, newMessageData(st, start)); err != nil {
+		return def
+	}
+	return b.String()
 }
 
 // Run invokes the CLI with the given arguments.
@@ -32,6 +104,45 @@ func (cli *CLI) Run(args []string) int {
 		simple  bool
 		version bool
 		help    bool
+		every   string
+		snooze  string
+		repeat  string
+		ceiling string
+		extra   alarmList
+		chime   string
+		message string
+		big     bool
+		bar     bool
+		format  string
+		output  string
+		color   string
+		refresh string
+		title   bool
+		via     string
+		tab     bool
+		desktop bool
+		tmux    bool
+		popup   bool
+		style   string
+		pattern string
+		count   int
+		on      string
+		off     string
+		forever bool
+		layers  string
+		alarms  alarmList
+		hook    string
+		bell    bool
+		tick    int
+		limit   string
+		tone    string
+		player  string
+		sink    string
+		loop    bool
+		ramp    string
+		hints   notificationStyle
+		expire  string
+		colors  string
 	)
 
 	// Define option flag parse
@@ -40,6 +151,52 @@ func (cli *CLI) Run(args []string) int {
 
 	flags.BoolVar(&simple, "simple", false, "(shortcut: s) Simple output which doesn't show remained seconds.")
 	flags.BoolVar(&simple, "s", false, "(shortcut: s) Simple output which doesn't show remained seconds.")
+	flags.StringVar(&every, "every", "", "Chime at every given interval without stopping the countdown.")
+	flags.StringVar(&chime, "chime", "bell", "Kind of chime used by --every: bell, flash or notify.")
+	flags.StringVar(&message, "message", "", "(shortcut: m) Message of the notification.")
+	flags.StringVar(&message, "m", "", "(shortcut: m) Message of the notification.")
+	flags.BoolVar(&big, "big", false, "Full-screen output with large digits.")
+	flags.BoolVar(&bar, "progress", false, "Show progress bar with percentage and ETA.")
+	flags.StringVar(&format, "format", "", "Template of the countdown line.")
+	flags.StringVar(&output, "output", "human", "Output format: human or json.")
+	flags.StringVar(&color, "color", "auto", "Color error messages: auto, always or never.")
+	flags.StringVar(&refresh, "refresh", "", "Interval of updating remained time.")
+	flags.StringVar(&snooze, "snooze", "10min", "Time to snooze the alarm from the notification.")
+	flags.StringVar(&repeat, "escalate", "", "Repeat the last alarm at the interval until it is acknowledged.")
+	flags.StringVar(&ceiling, "escalate-max", "10min", "How long --escalate repeats the alarm.")
+	flags.Var(&extra, "escalate-alarm", "Alarms added one by one at each repeat of --escalate.")
+	flags.BoolVar(&title, "title", false, "Show remained time in the terminal title.")
+	flags.StringVar(&via, "notifier", "dbus", "Notification of the alarm: dbus, libnotify, terminal, osc9, osc777 or osc99.")
+	flags.BoolVar(&tab, "tab-progress", false, "Show progress on the terminal tab with OSC 9;4.")
+	flags.BoolVar(&desktop, "progress-notification", false, "Show progress in a desktop notification.")
+	flags.BoolVar(&tmux, "tmux", false, "Publish remained time to tmux and show a message on expiry.")
+	flags.BoolVar(&popup, "tmux-popup", false, "Use display-popup instead of display-message with --tmux.")
+	flags.StringVar(&style, "flash-style", "auto", "Style of screen flashing: auto, reverse or background.")
+	flags.StringVar(&pattern, "flash", "blink", "Pattern of screen flashing: blink, sos or pulse.")
+	flags.IntVar(&count, "flash-count", 0, "Number of times the flash pattern is repeated.")
+	flags.StringVar(&on, "flash-on", "", "Duration of a flash.")
+	flags.StringVar(&off, "flash-off", "", "Duration between flashes.")
+	flags.BoolVar(&forever, "flash-until-ack", false, "Flash until a key is pressed or a signal is received.")
+	flags.Var(&alarms, "alarm", "Alarm fired when the timer is over, which can be repeated: "+strings.Join(alarmNames(), ", ")+".")
+	flags.StringVar(&hook, "alarm-command", "", "Shell command run by the command alarm.")
+	flags.BoolVar(&bell, "bell", false, "Ring the terminal bell in addition to the alarms.")
+	flags.IntVar(&tick, "bell-tick", 0, "Tick the bell every second during the final seconds.")
+	flags.StringVar(&limit, "bell-interval", "500ms", "Minimum interval between bells.")
+	flags.StringVar(&tone, "sound", "alarm", "Sound of the sound alarm: beep, chime, alarm or a file.")
+	flags.StringVar(&player, "sound-player", "", "Shell command which plays WAV from stdin.")
+	flags.StringVar(&sink, "sound-sink", "", "File or device where raw PCM is written instead of the player.")
+	flags.BoolVar(&loop, "sound-until-ack", false, "Repeat the sound until a key is pressed or a signal is received.")
+	flags.StringVar(&ramp, "sound-ramp", "", "Time the volume of the sound rises to full.")
+	flags.StringVar(&hints.urgency, "urgency", "normal", "Urgency of the notification: low, normal or critical.")
+	flags.StringVar(&expire, "expire", "default", "Time until the notification expires: TIME, never or default.")
+	flags.StringVar(&hints.category, "category", "", "Category of the notification.")
+	flags.StringVar(&hints.icon, "icon", "", "Icon name or image file of the notification.")
+	flags.StringVar(&hints.soundFile, "sound-file", "", "Sound file played by the notification server.")
+	flags.StringVar(&hints.soundName, "sound-name", "", "Themed sound played by the notification server.")
+	flags.BoolVar(&hints.resident, "resident", false, "Keep the notification after an action is chosen.")
+	flags.BoolVar(&hints.transient, "transient", false, "Keep the notification out of the history.")
+	flags.StringVar(&layers, "multiplexer", "auto", "Terminal multiplexers from the innermost: auto, none or list of tmux and screen.")
+	flags.StringVar(&colors, "thresholds", "50%,20%", "Remained time where --big turns yellow and red.")
 	flags.BoolVar(&version, "version", false, "(shortcut: v) Print version information and quit.")
 	flags.BoolVar(&version, "v", false, "(shortcut: v) Print version information and quit.")
 	flags.BoolVar(&help, "help", false, "(shortcut: h) Print this message.")
@@ -51,6 +208,28 @@ func (cli *CLI) Run(args []string) int {
 		return ExitCodeError
 	}
 
+	// Subcommand may be followed by flags.
+	command := ""
+	if flags.NArg() > 0 && (flags.Arg(0) == "run" || flags.Arg(0) == "validate") {
+		command = flags.Arg(0)
+		if err := flags.Parse(flags.Args()[1:]); err != nil {
+			return ExitCodeError
+		}
+	}
+
+	dumb := os.Getenv("TERM") == "dumb"
+	cli.tty = isTerminal(cli.outStream) && !dumb
+	switch color {
+	case "always":
+		cli.color = true
+	case "never":
+		cli.color = false
+	case "auto":
+		cli.color = isTerminal(cli.errStream) && !dumb && os.Getenv("NO_COLOR") == ""
+	default:
+		return cli.usageError("Unknown color: %s", color)
+	}
+
 	if help {
 		printUsage()
 		return ExitCodeOK
@@ -62,77 +241,718 @@ func (cli *CLI) Run(args []string) int {
 		return ExitCodeOK
 	}
 
-	d, err := getDuration(flags.Args())
+	var steps, concurrent []step
+	// recipe is the name of the recipe.
+	var recipe string
+	switch command {
+	case "validate":
+		return cli.validate(flags.Args())
+	case "run":
+		if flags.NArg() != 1 {
+			fmt.Fprintf(cli.errStream, "%sPlease specify a recipe file (%s -h)%s\n", cli.paint("31;1"), name, cli.paint("0"))
+			return ExitCodeError
+		}
+		r, err := loadRecipe(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(cli.errStream, "%s%v%s\n", cli.paint("31;1"), err, cli.paint("0"))
+			return ExitCodeError
+		}
+		steps, recipe = r.steps, r.name
+	default:
+		timers, ok, err := parseNamedTimers(flags.Args())
+		if ok {
+			if err != nil {
+				return cli.usageError("%v", err)
+			}
+			concurrent = timers
+			break
+		}
+		steps, err = parseSteps(flags.Args())
+		if err != nil {
+			return cli.usageError("%v", err)
+		}
+	}
+
+	opt := &options{simple: simple, chime: chime}
+	if every != "" {
+		i, err := parseInterval(every)
+		if err != nil || i < time.Second {
+			return cli.usageError("Invalid interval: %s", every)
+		}
+		opt.interval = int(i.Seconds())
+	}
+	if !isOneOf(chime, chimes) {
+		return cli.usageError("Unknown chime: %s", chime)
+	}
+	if message != "" {
+		t, err := template.New("message").Parse(message)
+		if err == nil {
+			err = t.Execute(ioutil.Discard, newMessageData(step{}, time.Now()))
+		}
+		if err != nil {
+			return cli.usageError("Invalid message: %v", err)
+		}
+		opt.message = t
+	}
+	thresholds, err := parseThresholds(colors)
 	if err != nil {
-		fmt.Fprintf(cli.errStream, "\033[31;1m%v\n", err)
-		fmt.Fprintf(cli.errStream, "\033[31;1mPlease check usage (%s -h)\033[0m\n", name)
-		return ExitCodeError
+		return cli.usageError("%v", err)
+	}
+	displays := 0
+	for _, b := range []bool{big, bar, format != ""} {
+		if b {
+			displays++
+		}
+	}
+	if displays > 1 {
+		return cli.usageError("Only one of --big, --progress and --format can be used")
+	}
+	if displays > 0 && concurrent != nil {
+		return cli.usageError("--big, --progress and --format cannot be used with NAME=TIME")
+	}
+	// The table of NAME=TIME is not driven through the display.
+	if (title || tab || tmux || popup || desktop) && concurrent != nil {
+		return cli.usageError("--title, --tab-progress, --tmux, --tmux-popup and --progress-notification cannot be used with NAME=TIME")
+	}
+	opt.thresholds = thresholds
+	switch {
+	case via == "terminal":
+		cli.notifier = detectOSC()
+	case via == "libnotify" && alarmBackends[via] == nil:
+		return cli.usageError("libnotify is not supported by this build. Please build with -tags libnotify")
+	case isOneOf(via, notifiers):
+		cli.notifier = via
+	default:
+		return cli.usageError("Unknown notifier: %s", via)
+	}
+	line := liveLine{w: cli.outStream, simple: simple, tty: cli.tty, refresh: 1}
+	if !cli.tty {
+		line.refresh = 60
+	}
+	if refresh != "" {
+		r, err := parseInterval(refresh)
+		if err != nil || r < time.Second {
+			return cli.usageError("Invalid refresh interval: %s", refresh)
+		}
+		line.refresh = int(r.Seconds())
+	}
+	opt.refresh = line.refresh
+	if opt.snooze, err = parseInterval(snooze); err != nil || opt.snooze < time.Second {
+		return cli.usageError("Invalid snooze interval: %s", snooze)
+	}
+	if repeat != "" {
+		e := escalation{alarms: extra}
+		if e.interval, err = parseInterval(repeat); err != nil || e.interval < time.Second {
+			return cli.usageError("Invalid escalation interval: %s", repeat)
+		}
+		if e.max, err = parseInterval(ceiling); err != nil || e.max <= 0 {
+			return cli.usageError("Invalid escalation duration: %s", ceiling)
+		}
+		if e.alarms == nil {
+			e.alarms = defaultEscalationAlarms
+		}
+		opt.escalation = e
+	}
+	if output != "human" && output != "json" {
+		return cli.usageError("Unknown output: %s", output)
+	}
+	var tmpl *template.Template
+	if format != "" {
+		if tmpl, err = parseFormat(format); err != nil {
+			return cli.usageError("Invalid format: %v", err)
+		}
+	}
+
+	// Human readable output goes to stderr with JSON lines on stdout.
+	if output == "json" {
+		opt.events = newEventWriter(cli.outStream)
+		c := *cli
+		cli = &c
+		cli.outStream = cli.errStream
+		cli.tty = isTerminal(cli.outStream) && !dumb
+		line.w, line.tty = cli.outStream, cli.tty
+	}
+	// The name of the recipe is a part of the human readable output.
+	if recipe != "" {
+		fmt.Fprintf(cli.outStream, "%s\n", recipe)
+	}
+	if big && !cli.tty {
+		fmt.Fprintf(cli.errStream, "--big is ignored since the output is not a terminal.\n")
+		big = false
+	}
+
+	if cli.multiplexers, err = parseMultiplexers(layers); err != nil {
+		return cli.usageError("%v", err)
+	}
+	if cli.multiplexers == nil {
+		cli.multiplexers = detectMultiplexers()
+	}
+	if !isOneOf(style, []string{"auto", flashReverse, flashBackground}) {
+		return cli.usageError("Unknown flash style: %s", style)
+	}
+	if cli.flash, err = parseFlashConfig(pattern, count, on, off); err != nil {
+		return cli.usageError("%v", err)
+	}
+	cli.flash.untilAck = forever
+
+	if hints.expire, err = parseExpire(expire); err != nil {
+		return cli.usageError("%v", err)
+	}
+	if err := hints.validate(); err != nil {
+		return cli.usageError("%v", err)
+	}
+	hints.icon = iconPath(hints.icon)
+	cli.notification = hints
+
+	// Without the notification server, --notifier dbus falls back to
+	// terminal notification before the default alarms are chosen. The
+	// notifier is used by the default alarms and the notify chime.
+	notifier := cli.notifier == "dbus" && (alarms == nil || opt.interval > 0 && chime == "notify")
+	if notifier || desktop || usesAlarm("dbus", append(alarms, extra...), append(steps, concurrent...)) {
+		if cli.bus, cli.busErr = dialNotifications(); cli.bus != nil {
+			defer cli.bus.close()
+		} else {
+			fallback := ""
+			if cli.notifier == "dbus" {
+				cli.notifier = detectOSC()
+				fallback = " Terminal notification is used instead."
+			}
+			fmt.Fprintf(cli.errStream, "Desktop notification is not available: %v.%s\n", cli.busErr, fallback)
+		}
 	}
-	rem := int(d.Seconds())
 
-	notify.Init("time-to-go")
-	sigCh := make(chan os.Signal)
-	signal.Notify(sigCh, os.Interrupt)
+	if cli.sound, err = loadSound(tone); err != nil {
+		return cli.usageError("Invalid sound: %v", err)
+	}
+	cli.sound.player, cli.sound.sink, cli.sound.untilAck = player, sink, loop
+	if ramp != "" {
+		if cli.sound.ramp, err = parseInterval(ramp); err != nil || cli.sound.ramp <= 0 {
+			return cli.usageError("Invalid sound ramp: %s", ramp)
+		}
+	}
+	if err := cli.sound.validate(); err != nil {
+		return cli.usageError("%v", err)
+	}
+
+	cli.bell = &bellRinger{w: cli.escapes()}
+	if cli.bell.interval, err = parseInterval(limit); err != nil || cli.bell.interval < 0 {
+		return cli.usageError("Invalid bell interval: %s", limit)
+	}
+	if tick < 0 {
+		return cli.usageError("Invalid bell tick: %d", tick)
+	}
+
+	cli.command = hook
+	if isOneOf("command", append(alarms, extra...)) && hook == "" {
+		return cli.usageError("--alarm-command is required for the command alarm")
+	}
+	opt.alarms = alarms
+	if opt.alarms == nil {
+		opt.alarms = cli.defaultAlarms()
+	}
+	if bell && !isOneOf("bell", opt.alarms) {
+		opt.alarms = append(opt.alarms, "bell")
+	}
+	// The probe of the flash style switches the terminal to raw mode, so
+	// it is done only when the screen flashes by the alarms in effect.
+	// The style given explicitly is used as it is.
+	if style != "auto" || cli.tty && opt.flashes(append(steps, concurrent...)) {
+		cli.flash.style = flashStyle(style, cli.outStream, cli.multiplexers)
+	}
+	// The tick needs a terminal unless the sound alarm plays it.
+	if cli.tty || isOneOf("sound", opt.alarms) {
+		opt.tick = tick
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	defer close(sigCh)
+	defer signal.Stop(sigCh)
+
+	switch {
+	case big:
+		opt.display = newBigDisplay(cli.outStream, thresholds, cli.color)
+	case bar:
+		opt.display = newProgressDisplay(line)
+	case tmpl != nil:
+		opt.display = &formatDisplay{liveLine: line, tmpl: tmpl}
+	default:
+		opt.display = &lineDisplay{line}
+	}
+	if title && cli.tty {
+		opt.display = newTitleDisplay(opt.display, cli.escapes())
+	}
+	if tab && cli.tty {
+		opt.display = &tabProgressDisplay{display: opt.display, w: cli.escapes()}
+	}
+	if tmux || popup {
+		if d := newTmuxDisplay(opt.display, cli.outStream, cli.tty, popup); d != nil {
+			opt.display = d
+		} else {
+			fmt.Fprintf(cli.errStream, "--tmux is ignored since TMUX is not set.\n")
+		}
+	}
+	if desktop {
+		if cli.bus != nil {
+			opt.display = &notificationDisplay{display: opt.display, bus: cli.bus}
+		} else {
+			fmt.Fprintf(cli.errStream, "--progress-notification is ignored since desktop notification is not available.\n")
+		}
+	}
+	defer opt.display.close()
+
+	run := cli.runSteps
+	if concurrent != nil {
+		steps, run = concurrent, cli.runConcurrent
+	}
+	if !run(steps, opt, sigCh) {
+		opt.display.close()
+		fmt.Fprintf(cli.errStream, "\nCancelled.\n")
+	}
+	return ExitCodeOK
+}
+
+// usageError prints the error message and tells to check usage.
+func (cli *CLI) usageError(format string, a ...interface{}) int {
+	fmt.Fprintf(cli.errStream, cli.paint("31;1")+format+"\n", a...)
+	fmt.Fprintf(cli.errStream, "%sPlease check usage (%s -h)%s\n", cli.paint("31;1"), name, cli.paint("0"))
+	return ExitCodeError
+}
+
+// paint returns SGR escape sequence of attr if messages are colored.
+func (cli *CLI) paint(attr string) string {
+	if !cli.color {
+		return ""
+	}
+	return "\033[" + attr + "m"
+}
+
+// runSteps runs steps one after another and triggers the alarm at the
+// end of each step. It returns false when the timer is cancelled.
+func (cli *CLI) runSteps(steps []step, opt *options, sigCh <-chan os.Signal) bool {
+	var in *bufio.Reader
+	if cli.inStream != nil {
+		in = bufio.NewReader(cli.inStream)
+	}
+
+	// ack is closed to stop the alarms in progress.
+	ack := make(chan struct{})
+	var g sync.WaitGroup
+	cancel := func() bool {
+		close(ack)
+		g.Wait()
+		return false
+	}
+	fire := func(s *status, n notice) {
+		opt.events.emit("fired", s)
+		g.Add(1)
+		go func(ack <-chan struct{}) {
+			cli.escalate(opt, s, n, ack)
+			g.Done()
+		}(ack)
+	}
+	// actions receives the action chosen on the notification of the
+	// last alarm.
+	actions := make(chan string, 1)
+	for i, st := range steps {
+		if st.instructions != "" {
+			fmt.Fprintf(cli.outStream, "%s\n", st.instructions)
+		}
+		if st.confirm && in != nil && !cli.confirm(in, sigCh) {
+			opt.events.emit("cancelled", &status{step: st, no: i + 1, total: len(steps), remains: int(st.duration.Seconds())})
+			return cancel()
+		}
+
+		// Sub-timers fire their own alarm while the step is running. They
+		// are paused along with the step.
+		start := time.Now()
+		done := make(chan struct{})
+		pause := newPauseState()
+		// Their alarms run in g not to delay the next step. The
+		// sub-timers which have not fired are stopped on cancel before
+		// waiting for g.
+		var sub sync.WaitGroup
+		for _, p := range st.parallel {
+			sub.Add(1)
+			go func(p step, ack <-chan struct{}) {
+				if !pause.sleep(p.duration, done) {
+					sub.Done()
+					return
+				}
+				body := "Parallel timer is over."
+				if p.label != "" {
+					body = p.label + " is over."
+				}
+				s := &status{step: p, start: start}
+				opt.events.emit("fired", s)
+				g.Add(1)
+				sub.Done()
+				cli.alarm(opt, s, notice{summary: notificationSummary(p, ""), body: opt.body(p, start, body)}, ack)
+				g.Done()
+			}(p, ack)
+		}
+		subDone := make(chan struct{})
+		go func() {
+			sub.Wait()
+			close(subDone)
+		}()
+
+		if !cli.countdown(st, i+1, len(steps), opt, pause, &g, ack, sigCh) {
+			close(done)
+			sub.Wait()
+			return cancel()
+		}
+
+		progress, body := "", "Wake up!!!!"
+		if st.label != "" {
+			body = st.label + " is over."
+		}
+		if len(steps) > 1 {
+			progress = fmt.Sprintf(" (step %d/%d)", i+1, len(steps))
+			if i+1 < len(steps) {
+				body = fmt.Sprintf("%s is over. Next: %s", steps[i].name(i+1), steps[i+1].name(i+2))
+			}
+		}
+		n := notice{summary: notificationSummary(st, progress), body: opt.body(st, start, body), last: i+1 == len(steps)}
+		if n.last {
+			n.actions = actions
+		}
+		fire(&status{step: st, no: i + 1, total: len(steps), start: start}, n)
+
+		// The next step starts after the sub-timers are over, which
+		// can still be paused.
+		if !cli.waitParallel(subDone, pause, &status{step: st, no: i + 1, total: len(steps), start: start}, opt, sigCh) {
+			close(done)
+			sub.Wait()
+			return cancel()
+		}
+		close(done)
+	}
+
+	// The last alarm can be snoozed or extended from the notification.
+	for {
+		var d time.Duration
+		switch cli.waitAlarms(&g, ack, sigCh, actions) {
+		case "snooze":
+			d = opt.snooze
+		case "extend":
+			d = extension
+		default:
+			return true
+		}
+		last := steps[len(steps)-1]
+		st := step{duration: d, label: last.label, alarms: last.alarms, notification: last.notification}
+		opt.events.emit("snoozed", &status{step: st, remains: int(d.Seconds())})
+		ack = make(chan struct{})
+		start := time.Now()
+		if !cli.countdown(st, 1, 1, opt, nil, &g, ack, sigCh) {
+			return cancel()
+		}
+		body := "Wake up!!!!"
+		if st.label != "" {
+			body = st.label + " is over."
+		}
+		fire(&status{step: st, start: start}, notice{summary: notificationSummary(st, ""), body: opt.body(st, start, body), last: true, actions: actions})
+	}
+}
+
+// confirm waits for Enter. It returns false when the timer is cancelled.
+func (cli *CLI) confirm(in *bufio.Reader, sigCh <-chan os.Signal) bool {
+	fmt.Fprintf(cli.outStream, "Press Enter to start...")
+	line := make(chan struct{})
+	go func() {
+		in.ReadString('\n')
+		close(line)
+	}()
+	select {
+	case <-line:
+		return true
+	case <-sigCh:
+		return false
+	}
+}
+
+// validate checks recipe files without running them.
+func (cli *CLI) validate(paths []string) int {
+	if len(paths) == 0 {
+		fmt.Fprintf(cli.errStream, "%sPlease specify recipe files (%s -h)%s\n", cli.paint("31;1"), name, cli.paint("0"))
+		return ExitCodeError
+	}
+	status := ExitCodeOK
+	for _, p := range paths {
+		if _, err := loadRecipe(p); err != nil {
+			fmt.Fprintf(cli.errStream, "%v\n", err)
+			status = ExitCodeError
+			continue
+		}
+		fmt.Fprintf(cli.outStream, "%s: OK\n", p)
+	}
+	return status
+}
+
+// waitParallel waits for the sub-timers of the step of s until done is
+// closed, pausing and resuming them by SIGUSR1. It returns false when
+// they are cancelled.
+func (cli *CLI) waitParallel(done <-chan struct{}, pause *pauseState, s *status, opt *options, sigCh <-chan os.Signal) bool {
+	pauseCh := make(chan os.Signal, 1)
+	notifyPause(pauseCh)
+	defer signal.Stop(pauseCh)
+	for {
+		select {
+		case <-done:
+			return true
+		case <-pauseCh:
+			s.paused = !s.paused
+			pause.set(s.paused)
+			if s.paused {
+				opt.events.emit("paused", s)
+			} else {
+				opt.events.emit("resumed", s)
+			}
+		case <-sigCh:
+			opt.events.emit("cancelled", s)
+			return false
+		}
+	}
+}
+
+// countdown waits for the duration of st showing remained time.
+// no and total are the position of st in the chain given by --then.
+// SIGUSR1 pauses and resumes the countdown with pause. The chimes and the
+// ticks run in g until ack is closed.
+// It returns false when the timer is cancelled.
+func (cli *CLI) countdown(st step, no, total int, opt *options, pause *pauseState, g *sync.WaitGroup, ack <-chan struct{}, sigCh <-chan os.Signal) bool {
+	d := st.duration
+	s := &status{step: st, no: no, total: total, remains: int(d.Seconds()), start: time.Now()}
+	deadline := s.start.Add(d)
+
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
-	stop := make(chan bool)
-	defer close(stop)
-	fmt.Printf("Sleeping %v\n", strings.Replace(d.String(), "m", "min", 1))
-	go func() {
-	loop:
-		for {
-			select {
-			case <-ticker.C:
-				if simple != true {
-					rem--
-					if rem != 0 {
-						min := rem / 60
-						sec := rem % 60
-						hour := min / 60
-						min = min % 60
-						timeLimit := ""
-						switch {
-						case hour > 0:
-							timeLimit = fmt.Sprintf("%02vh%02vmin%02vs", hour, min, sec)
-						case min > 0:
-							timeLimit = fmt.Sprintf("   %02vmin%02vs", min, sec)
-						default:
-							timeLimit = fmt.Sprintf("        %02vs", sec)
-						}
-						fmt.Fprintf(cli.outStream, "\r%s remains...", timeLimit)
-					} else {
-						fmt.Fprintf(cli.outStream, "\r  0 sec(s) remains...\n")
-						break loop
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	pauseCh := make(chan os.Signal, 1)
+	notifyPause(pauseCh)
+	defer signal.Stop(pauseCh)
+
+	opt.display.begin(s)
+	opt.events.emit("started", s)
+	level := opt.thresholds.level(s.remains, d)
+	var left time.Duration
+	for {
+		select {
+		case <-sigCh:
+			opt.events.emit("cancelled", s)
+			return false
+		case <-pauseCh:
+			if s.paused {
+				s.paused = false
+				deadline = time.Now().Add(left)
+				s.start = deadline.Add(-d)
+				timer.Reset(left)
+				pause.set(false)
+				opt.events.emit("resumed", s)
+			} else {
+				s.paused = true
+				left = time.Until(deadline)
+				// The timer which has fired is drained not to expire
+				// right after the resume.
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
 					}
 				}
-			case <-stop:
-				break loop
+				pause.set(true)
+				opt.events.emit("paused", s)
+			}
+			opt.display.update(s)
+		case <-timer.C:
+			s.remains = 0
+			opt.display.end(s)
+			return true
+		case <-ticker.C:
+			if s.paused {
+				continue
+			}
+			s.remains = int((time.Until(deadline) + time.Second/2).Seconds())
+			if s.remains <= 0 {
+				// timer expires soon.
+				continue
+			}
+			if elapsed := int(d.Seconds()) - s.remains; opt.interval > 0 && elapsed%opt.interval == 0 {
+				g.Add(1)
+				go func() {
+					cli.ringChime(opt.chime, time.Duration(elapsed)*time.Second, ack)
+					g.Done()
+				}()
+			}
+			if s.remains <= opt.tick {
+				cli.tickBell(opt, g, ack)
+			}
+			opt.display.update(s)
+			opt.events.emit("tick", s)
+			if l := opt.thresholds.level(s.remains, d); l > level {
+				level = l
+				opt.events.emitLevel("warning", s, []string{"", "yellow", "red"}[l])
 			}
 		}
-	}()
+	}
+}
 
-	select {
-	case <-sigCh:
-		fmt.Fprintf(cli.errStream, "\nCancelled.\n")
-		return ExitCodeOK
-	case <-time.After(d):
-		break
+// tickBell rings the bell for a tick of the final seconds. If the sound
+// alarm is chosen, the short tick sound is played instead in g until ack
+// is closed.
+func (cli *CLI) tickBell(opt *options, g *sync.WaitGroup, ack <-chan struct{}) {
+	if isOneOf("sound", opt.alarms) {
+		sound := cli.sound
+		sound.pcm, sound.data, sound.untilAck, sound.ramp = tickSound, nil, false, 0
+		g.Add(1)
+		go func() {
+			soundAlarm{sound: sound, w: cli.errStream}.Fire(notice{}, ack)
+			g.Done()
+		}()
+		return
 	}
+	if cli.tty {
+		cli.bell.ring()
+	}
+}
+
+// ringChime triggers a lightweight alert which tells elapsed time
+// without stopping the countdown. It is distinct from the alarm fired
+// when the timer expires. It stops when ack is closed.
+func (cli *CLI) ringChime(kind string, elapsed time.Duration, ack <-chan struct{}) {
+	n := notice{summary: "time-to-go", body: formatDuration(elapsed) + " elapsed", style: notificationStyle{urgency: "low"}, chime: true}
+	switch {
+	case kind == "notify":
+		if alarmBackends[cli.notifierAlarm()](cli).Fire(n, ack) == nil {
+			return
+		}
+	case !cli.tty:
+		// The bell and the flash would leave escape sequences in the
+		// redirected output, so a line is printed instead.
+	case kind == "bell":
+		cli.bell.ring()
+		return
+	case kind == "flash":
+		flash := cli.flash
+		flash.pattern, flash.count, flash.untilAck = "blink", 1, false
+		flashScreen(cli.escapes(), flash, ack)
+		return
+	}
+	printAlarm{cli.outStream}.Fire(n, ack)
+}
+
+// escapes returns the writer of escape sequences to the terminal, which
+// passes them through the multiplexers.
+func (cli *CLI) escapes() io.Writer {
+	return passthrough{w: cli.outStream, layers: cli.multiplexers}
+}
+
+// defaultAlarms returns the alarms fired unless --alarm is given, which
+// are desktop or terminal notification with screen flashing. Terminal
+// notification is written as a line when the output is not a terminal.
+func (cli *CLI) defaultAlarms() []string {
+	alarms := []string{cli.notifierAlarm()}
+	if alarms[0] == "osc" && !cli.tty {
+		alarms[0] = "print"
+	}
+	if cli.tty {
+		alarms = append(alarms, "flash")
+	}
+	return alarms
+}
+
+// notifierAlarm returns the name of the alarm which shows the
+// notification of --notifier.
+func (cli *CLI) notifierAlarm() string {
+	if cli.notifier == "dbus" || cli.notifier == "libnotify" {
+		return cli.notifier
+	}
+	return "osc"
+}
 
+// alarm fires the alarms of s at the same time with the notification
+// style of the step. The alarms stop when ack
+// is closed. If none of them succeeded, fallbackAlarms are fired instead.
+// The alarms which failed are reported to errStream and the ones which
+// fired are emitted as the alarm event.
+func (cli *CLI) alarm(opt *options, s *status, n notice, ack <-chan struct{}) {
+	names := s.step.alarms
+	if names == nil {
+		names = opt.alarms
+	}
+	n.style = cli.notification.merge(s.step.notification)
+	errs := make([]error, len(names))
 	var g sync.WaitGroup
-	g.Add(2)
-	go func() {
-		notify.Init("time-to-go")
-		n := notify.NotificationNew("time-to-go", "Wake up!!!!", "appointment-soon")
-		n.Show()
-		g.Done()
-	}()
+	for i, name := range names {
+		g.Add(1)
+		go func(i int, a Alarm) {
+			errs[i] = a.Fire(n, ack)
+			g.Done()
+		}(i, alarmBackends[name](cli))
+	}
+	g.Wait()
+
+	var fired, failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%v)", names[i], err))
+			continue
+		}
+		fired = append(fired, names[i])
+	}
+	if len(fired) == 0 {
+		for _, name := range fallbackAlarms {
+			if alarmBackends[name](cli).Fire(n, ack) == nil {
+				fired = append(fired, name)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(cli.errStream, "Alarm failed: %s. Fired: %s\n", strings.Join(failed, ", "), strings.Join(fired, ", "))
+	}
+	opt.events.emitAlarm(s, fired)
+}
+
+// waitAlarms waits for the alarms in g. A key pressed on the terminal, a
+// signal or an action on the notification acknowledges them, which is
+// told to them by closing ack. It returns the action if it is chosen.
+func (cli *CLI) waitAlarms(g *sync.WaitGroup, ack chan struct{}, sigCh <-chan os.Signal, actions <-chan string) string {
+	done := make(chan struct{})
 	go func() {
-		flashScreen(6)
-		g.Done()
+		g.Wait()
+		close(done)
 	}()
-
-	g.Wait()
-	return ExitCodeOK
+	key := make(chan struct{})
+	var reading sync.WaitGroup
+	if cli.tty {
+		reading.Add(1)
+		go func() {
+			if readKey(done) {
+				close(key)
+			}
+			reading.Done()
+		}()
+	}
+	action := ""
+	select {
+	case <-done:
+	case <-key:
+		close(ack)
+	case <-sigCh:
+		close(ack)
+	case action = <-actions:
+		close(ack)
+	}
+	<-done
+	// The terminal mode is restored after reading the key.
+	reading.Wait()
+	if action == "" {
+		select {
+		case action = <-actions:
+		default:
+		}
+	}
+	return action
 }
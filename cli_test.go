@@ -2,9 +2,14 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"os"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestRun_versionFlag(t *testing.T) {
@@ -22,3 +27,98 @@ func TestRun_versionFlag(t *testing.T) {
 		t.Errorf("expected %q to eq %q", errStream.String(), expected)
 	}
 }
+
+func TestRun_namedTimersDisplay(t *testing.T) {
+	for _, arg := range []string{"--big", "--title", "--tab-progress", "--tmux", "--tmux-popup", "--progress-notification"} {
+		var errs bytes.Buffer
+		cli := &CLI{outStream: &bytes.Buffer{}, errStream: &errs}
+		if status := cli.Run(strings.Split("time-to-go "+arg+" pasta=1s", " ")); status != ExitCodeError {
+			t.Errorf("expected %d to eq %d for %s", status, ExitCodeError, arg)
+		}
+		if !strings.Contains(errs.String(), "cannot be used with NAME=TIME") {
+			t.Errorf("expected %q to reject %s", errs.String(), arg)
+		}
+	}
+}
+
+// lockedBuffer is bytes.Buffer written by the alarms running concurrently.
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestRun_namedTimers(t *testing.T) {
+	var out lockedBuffer
+	var errs bytes.Buffer
+	cli := &CLI{outStream: &out, errStream: &errs}
+	if status := cli.Run(strings.Split("time-to-go --alarm print café=1s tea=1s", " ")); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errs.String())
+	}
+	// The names are padded by characters, not by bytes.
+	expected := []string{"café " + formatRemains(1) + " remains...", "tea  " + formatRemains(1) + " remains..."}
+	if lines := strings.Split(out.String(), "\n"); !reflect.DeepEqual(lines[:2], expected) {
+		t.Errorf("expected %q to eq %q", lines[:2], expected)
+	}
+	// The print alarm reports the timers without the plain lines.
+	if n := strings.Count(out.String(), "is over."); n != 2 {
+		t.Errorf("expected %q to report the timers once", out.String())
+	}
+}
+
+func TestCLI_runStepsCancelFlash(t *testing.T) {
+	var out lockedBuffer
+	cli := &CLI{outStream: &out, errStream: &bytes.Buffer{}, tty: true, flash: flashConfig{count: 1, on: time.Minute}}
+	opt := &options{interval: 1, chime: "flash", display: &lineDisplay{liveLine{w: &out, simple: true}}}
+	sigCh := make(chan os.Signal, 1)
+	// The chime at a second is still flashing when the timer is cancelled.
+	time.AfterFunc(1500*time.Millisecond, func() {
+		sigCh <- os.Interrupt
+	})
+	if cli.runSteps([]step{{duration: 5 * time.Second}}, opt, sigCh) {
+		t.Fatal("expected the timer to be cancelled")
+	}
+	if !strings.HasSuffix(out.String(), "\x1b[?5h\x1b[?5l") {
+		t.Errorf("expected %q to end in normal video", out.String())
+	}
+}
+
+func TestCLI_runStepsParallelAlarm(t *testing.T) {
+	var out, events lockedBuffer
+	cli := &CLI{outStream: &out, errStream: &bytes.Buffer{}, command: "sleep 3"}
+	opt := &options{display: &lineDisplay{liveLine{w: &out, simple: true}}, events: newEventWriter(&events)}
+	steps := []step{
+		{duration: time.Second, alarms: []string{"print"}, parallel: []step{{duration: 100 * time.Millisecond, alarms: []string{"command"}}}},
+		{duration: time.Second, alarms: []string{"print"}},
+	}
+	start := time.Now()
+	if !cli.runSteps(steps, opt, make(chan os.Signal)) {
+		t.Fatal("expected the steps to finish")
+	}
+	// The next step doesn't wait for the alarm of the sub-timer.
+	dec := json.NewDecoder(strings.NewReader(events.String()))
+	for dec.More() {
+		var ev event
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Event == "started" && ev.Step == 2 {
+			if d := ev.Time.Sub(start); d > 2*time.Second {
+				t.Errorf("expected the second step to start at a second, got %v", d)
+			}
+			return
+		}
+	}
+	t.Errorf("expected the second step to start in %q", events.String())
+}
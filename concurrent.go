@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// runConcurrent runs named timers at the same time, rendering them as a
+// live table. Each timer triggers its own alarm when it expires while
+// the others continue. SIGUSR1 pauses and resumes all of them. It
+// returns false when the timers are cancelled.
+func (cli *CLI) runConcurrent(timers []step, opt *options, sigCh <-chan os.Signal) bool {
+	width := 0
+	for _, t := range timers {
+		if n := utf8.RuneCountInString(t.label); n > width {
+			width = n
+		}
+	}
+
+	start := time.Now()
+	fired := make([]bool, len(timers))
+	left := len(timers)
+	// paused is when the timers are paused, which is zero while they are
+	// running. start is shifted by the pause on resume.
+	var paused time.Time
+	now := func() time.Time {
+		if paused.IsZero() {
+			return time.Now()
+		}
+		return paused
+	}
+	remains := func(t step) int {
+		return int((t.duration - now().Sub(start) + time.Second/2).Seconds())
+	}
+	state := func(t step) *status {
+		return &status{step: t, remains: remains(t), start: start, paused: !paused.IsZero()}
+	}
+	// On a terminal the table is redrawn in place every second.
+	// Otherwise it is written every refresh seconds.
+	render := func(first bool) {
+		if opt.simple {
+			return
+		}
+		if !cli.tty {
+			if sec := int(time.Since(start).Seconds() + 0.5); !first && sec%opt.refresh != 0 {
+				return
+			}
+		} else if !first {
+			// Move the cursor back to the top of the table.
+			fmt.Fprintf(cli.outStream, "\x1b[%dA", len(timers))
+		}
+		for i, t := range timers {
+			row := fmt.Sprintf("%11s", "done")
+			if !fired[i] {
+				s := state(t)
+				row = formatRemains(s.remains) + " remains..." + s.pausedMark()
+			}
+			if cli.tty {
+				fmt.Fprint(cli.outStream, "\r\x1b[K")
+			}
+			fmt.Fprintf(cli.outStream, "%-*s %s\n", width, t.label, row)
+		}
+	}
+	render(true)
+	for _, t := range timers {
+		opt.events.emit("started", &status{step: t, remains: int(t.duration.Seconds()), start: start})
+	}
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	pauseCh := make(chan os.Signal, 1)
+	notifyPause(pauseCh)
+	defer signal.Stop(pauseCh)
+	// ack is closed to stop the alarms in progress.
+	ack := make(chan struct{})
+	var g sync.WaitGroup
+	for left > 0 {
+		select {
+		case <-sigCh:
+			for i, t := range timers {
+				if !fired[i] {
+					opt.events.emit("cancelled", state(t))
+				}
+			}
+			close(ack)
+			g.Wait()
+			return false
+		case <-pauseCh:
+			kind := "paused"
+			if paused.IsZero() {
+				paused = time.Now()
+			} else {
+				start = start.Add(time.Since(paused))
+				paused = time.Time{}
+				kind = "resumed"
+			}
+			for i, t := range timers {
+				if !fired[i] {
+					opt.events.emit(kind, state(t))
+				}
+			}
+			render(false)
+			continue
+		case <-ticker.C:
+			if !paused.IsZero() {
+				continue
+			}
+		}
+		elapsed := time.Since(start)
+		ticking := false
+		for i, t := range timers {
+			if fired[i] {
+				continue
+			}
+			if elapsed+time.Second/2 < t.duration {
+				opt.events.emit("tick", state(t))
+				ticking = ticking || remains(t) <= opt.tick
+				continue
+			}
+			fired[i] = true
+			s := &status{step: t, start: start}
+			opt.events.emit("fired", s)
+			left--
+			alarms := t.alarms
+			if alarms == nil {
+				alarms = opt.alarms
+			}
+			// The print alarm reports it by itself.
+			if (opt.simple || !cli.tty) && !isOneOf("print", alarms) {
+				fmt.Fprintf(cli.outStream, "%s is over.\n", t.label)
+			}
+			g.Add(1)
+			n := notice{summary: notificationSummary(t, ""), body: opt.body(t, start, t.label+" is over."), last: left == 0}
+			go func() {
+				cli.escalate(opt, s, n, ack)
+				g.Done()
+			}()
+		}
+		if sec := int(elapsed.Seconds() + 0.5); opt.interval > 0 && left > 0 && sec%opt.interval == 0 {
+			g.Add(1)
+			go func() {
+				cli.ringChime(opt.chime, time.Duration(sec)*time.Second, ack)
+				g.Done()
+			}()
+		}
+		if ticking {
+			cli.tickBell(opt, &g, ack)
+		}
+		render(false)
+	}
+	cli.waitAlarms(&g, ack, sigCh, nil)
+	return true
+}
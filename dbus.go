@@ -0,0 +1,898 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file implements the part of the D-Bus protocol needed to talk to
+// the notification server without cgo: unix socket transport, EXTERNAL
+// authentication and the wire format of messages.
+
+// Types of D-Bus messages.
+const (
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusError        = 3
+	dbusSignal       = 4
+)
+
+// Header fields of D-Bus messages.
+const (
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSender      = 7
+	dbusFieldSignature   = 8
+)
+
+// The message bus itself.
+const (
+	busName = "org.freedesktop.DBus"
+	busPath = "/org/freedesktop/DBus"
+)
+
+// dbusTimeout is the time to wait for the reply of a method call.
+var dbusTimeout = 5 * time.Second
+
+// dbusMessage is a D-Bus message. body holds the values of signature.
+// Basic types are represented by Go types of the same size (y is byte,
+// b is bool, i is int32, ...), s, o and g by string, arrays by
+// []interface{} except dictionaries with string keys which are
+// map[string]interface{}, and structs by []interface{}. Variants are
+// decoded into their values.
+type dbusMessage struct {
+	kind        byte
+	flags       byte
+	serial      uint32
+	path        string
+	iface       string
+	member      string
+	errorName   string
+	replySerial uint32
+	destination string
+	sender      string
+	signature   string
+	body        []interface{}
+}
+
+// dbusVariant is a value encoded as a variant with an explicit signature.
+// Other values in a variant get their signature from the Go type.
+type dbusVariant struct {
+	sig   string
+	value interface{}
+}
+
+// dbusConn is a connection to a message bus.
+type dbusConn struct {
+	conn net.Conn
+	// name is the unique name of the connection given by the bus.
+	name string
+	// incoming receives signals and method calls. They are dropped when
+	// nobody receives them.
+	incoming chan *dbusMessage
+	// done is closed when the connection is lost.
+	done chan struct{}
+
+	wmu     sync.Mutex
+	mu      sync.Mutex
+	serial  uint32
+	replies map[uint32]chan *dbusMessage
+	err     error
+}
+
+// dialSessionBus connects to the session bus of the user.
+func dialSessionBus() (*dbusConn, error) {
+	address := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+	if address == "" {
+		dir := os.Getenv("XDG_RUNTIME_DIR")
+		if dir == "" {
+			return nil, errors.New("DBUS_SESSION_BUS_ADDRESS is not set")
+		}
+		address = "unix:path=" + dir + "/bus"
+	}
+	return dialBus(address)
+}
+
+// dialBus connects to the first available bus of the address, which is
+// a list separated by ";".
+func dialBus(address string) (*dbusConn, error) {
+	err := fmt.Errorf("invalid D-Bus address: %s", address)
+	for _, a := range strings.Split(address, ";") {
+		var conn net.Conn
+		if conn, err = dialAddress(a); err != nil {
+			continue
+		}
+		var c *dbusConn
+		if c, err = newDBusConn(conn); err != nil {
+			conn.Close()
+			continue
+		}
+		return c, nil
+	}
+	return nil, err
+}
+
+// dialAddress connects to a unix socket given as "unix:path=..." or
+// "unix:abstract=...".
+func dialAddress(a string) (net.Conn, error) {
+	i := strings.Index(a, ":")
+	if i < 0 || a[:i] != "unix" {
+		return nil, fmt.Errorf("unsupported D-Bus address: %s", a)
+	}
+	for _, kv := range strings.Split(a[i+1:], ",") {
+		j := strings.Index(kv, "=")
+		if j < 0 {
+			continue
+		}
+		v, err := url.PathUnescape(kv[j+1:])
+		if err != nil {
+			return nil, err
+		}
+		switch kv[:j] {
+		case "path":
+			return net.DialTimeout("unix", v, dbusTimeout)
+		case "abstract":
+			return net.DialTimeout("unix", "@"+v, dbusTimeout)
+		}
+	}
+	return nil, fmt.Errorf("unsupported D-Bus address: %s", a)
+}
+
+// newDBusConn authenticates conn and says Hello to the bus.
+func newDBusConn(conn net.Conn) (*dbusConn, error) {
+	// The bus which doesn't answer must not block the startup. The
+	// deadline is cleared for the messages, which time out by call.
+	if err := conn.SetDeadline(time.Now().Add(dbusTimeout)); err != nil {
+		return nil, err
+	}
+	// A nul byte is required before the authentication.
+	uid := strconv.Itoa(os.Getuid())
+	if _, err := fmt.Fprintf(conn, "\x00AUTH EXTERNAL %s\r\n", hex.EncodeToString([]byte(uid))); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return nil, fmt.Errorf("D-Bus authentication failed: %s", strings.TrimSpace(line))
+	}
+	if _, err := fmt.Fprint(conn, "BEGIN\r\n"); err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	c := &dbusConn{
+		conn:     conn,
+		incoming: make(chan *dbusMessage, 64),
+		done:     make(chan struct{}),
+		replies:  map[uint32]chan *dbusMessage{},
+	}
+	go c.readLoop(r)
+	reply, err := c.call(busName, busPath, busName, "Hello", "")
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	if len(reply) > 0 {
+		c.name, _ = reply[0].(string)
+	}
+	return c, nil
+}
+
+// close closes the connection.
+func (c *dbusConn) close() error {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = errors.New("D-Bus connection is closed")
+	}
+	c.mu.Unlock()
+	return c.conn.Close()
+}
+
+func (c *dbusConn) readLoop(r io.Reader) {
+	for {
+		m, err := readDBusMessage(r)
+		if err != nil {
+			c.mu.Lock()
+			if c.err == nil {
+				c.err = err
+			}
+			c.mu.Unlock()
+			close(c.done)
+			return
+		}
+		switch m.kind {
+		case dbusMethodReturn, dbusError:
+			c.mu.Lock()
+			ch := c.replies[m.replySerial]
+			delete(c.replies, m.replySerial)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- m
+			}
+		default:
+			select {
+			case c.incoming <- m:
+			default:
+			}
+		}
+	}
+}
+
+// send writes m to the bus. The serial is assigned if it is zero.
+func (c *dbusConn) send(m *dbusMessage) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	if m.serial == 0 {
+		c.serial++
+		m.serial = c.serial
+	}
+	c.mu.Unlock()
+	data, err := m.marshal()
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err = c.conn.Write(data)
+	return err
+}
+
+// call calls the method and returns the body of the reply.
+func (c *dbusConn) call(dest, path, iface, member, sig string, args ...interface{}) ([]interface{}, error) {
+	c.mu.Lock()
+	c.serial++
+	serial := c.serial
+	ch := make(chan *dbusMessage, 1)
+	c.replies[serial] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.replies, serial)
+		c.mu.Unlock()
+	}()
+
+	m := &dbusMessage{kind: dbusMethodCall, serial: serial, path: path, iface: iface, member: member, destination: dest, signature: sig, body: args}
+	if err := c.send(m); err != nil {
+		return nil, err
+	}
+	t := time.NewTimer(dbusTimeout)
+	defer t.Stop()
+	select {
+	case r := <-ch:
+		if r.kind == dbusError {
+			msg := r.errorName
+			if len(r.body) > 0 {
+				if s, ok := r.body[0].(string); ok {
+					msg += ": " + s
+				}
+			}
+			return nil, errors.New(msg)
+		}
+		return r.body, nil
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return nil, c.err
+	case <-t.C:
+		return nil, fmt.Errorf("no reply to %s.%s", iface, member)
+	}
+}
+
+// marshal encodes m in little endian.
+func (m *dbusMessage) marshal() ([]byte, error) {
+	types, err := splitSignature(m.signature)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) != len(m.body) {
+		return nil, fmt.Errorf("signature %q doesn't match %d values", m.signature, len(m.body))
+	}
+	var body dbusEncoder
+	for i, t := range types {
+		if err := body.encode(t, m.body[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var fields []interface{}
+	field := func(code byte, sig string, v interface{}) {
+		fields = append(fields, []interface{}{code, dbusVariant{sig, v}})
+	}
+	if m.path != "" {
+		field(dbusFieldPath, "o", m.path)
+	}
+	if m.iface != "" {
+		field(dbusFieldInterface, "s", m.iface)
+	}
+	if m.member != "" {
+		field(dbusFieldMember, "s", m.member)
+	}
+	if m.errorName != "" {
+		field(dbusFieldErrorName, "s", m.errorName)
+	}
+	if m.replySerial != 0 {
+		field(dbusFieldReplySerial, "u", m.replySerial)
+	}
+	if m.destination != "" {
+		field(dbusFieldDestination, "s", m.destination)
+	}
+	if m.signature != "" {
+		field(dbusFieldSignature, "g", m.signature)
+	}
+
+	var e dbusEncoder
+	e.buf.Write([]byte{'l', m.kind, m.flags, 1})
+	e.uint32(uint32(body.buf.Len()))
+	e.uint32(m.serial)
+	if err := e.encode("a(yv)", fields); err != nil {
+		return nil, err
+	}
+	e.align(8)
+	e.buf.Write(body.buf.Bytes())
+	return e.buf.Bytes(), nil
+}
+
+// readDBusMessage reads a message from r.
+func readDBusMessage(r io.Reader) (*dbusMessage, error) {
+	head := make([]byte, 16)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder
+	switch head[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid D-Bus message")
+	}
+	bodyLen, fieldsLen := order.Uint32(head[4:]), order.Uint32(head[12:])
+	// The maximum length of a message is 128 MiB.
+	if bodyLen > 1<<27 || fieldsLen > 1<<26 {
+		return nil, fmt.Errorf("too long D-Bus message")
+	}
+	headerLen := 16 + int(fieldsLen)
+	bodyStart := (headerLen + 7) / 8 * 8
+	data := make([]byte, bodyStart+int(bodyLen))
+	copy(data, head)
+	if _, err := io.ReadFull(r, data[16:]); err != nil {
+		return nil, err
+	}
+
+	m := &dbusMessage{kind: head[1], flags: head[2], serial: order.Uint32(head[8:])}
+	d := &dbusDecoder{data: data[:headerLen], pos: 12, order: order}
+	v, err := d.decode("a(yv)")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range v.([]interface{}) {
+		f := f.([]interface{})
+		code, value := f[0].(byte), f[1]
+		s, _ := value.(string)
+		switch code {
+		case dbusFieldPath:
+			m.path = s
+		case dbusFieldInterface:
+			m.iface = s
+		case dbusFieldMember:
+			m.member = s
+		case dbusFieldErrorName:
+			m.errorName = s
+		case dbusFieldReplySerial:
+			m.replySerial, _ = value.(uint32)
+		case dbusFieldDestination:
+			m.destination = s
+		case dbusFieldSender:
+			m.sender = s
+		case dbusFieldSignature:
+			m.signature = s
+		}
+	}
+
+	types, err := splitSignature(m.signature)
+	if err != nil {
+		return nil, err
+	}
+	d = &dbusDecoder{data: data[bodyStart:], order: order}
+	for _, t := range types {
+		v, err := d.decode(t)
+		if err != nil {
+			return nil, err
+		}
+		m.body = append(m.body, v)
+	}
+	return m, nil
+}
+
+// splitSignature splits sig into single complete types.
+func splitSignature(sig string) ([]string, error) {
+	var types []string
+	for sig != "" {
+		n, err := completeType(sig)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, sig[:n])
+		sig = sig[n:]
+	}
+	return types, nil
+}
+
+// completeType returns the length of the first complete type in sig.
+func completeType(sig string) (int, error) {
+	if sig == "" {
+		return 0, errors.New("invalid signature")
+	}
+	switch sig[0] {
+	case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 's', 'o', 'g', 'v':
+		return 1, nil
+	case 'a':
+		n, err := completeType(sig[1:])
+		return n + 1, err
+	case '(', '{':
+		end := byte(')')
+		if sig[0] == '{' {
+			end = '}'
+		}
+		for i := 1; i < len(sig); {
+			if sig[i] == end {
+				return i + 1, nil
+			}
+			n, err := completeType(sig[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	return 0, fmt.Errorf("invalid signature %q", sig)
+}
+
+// alignment returns the alignment of the type sig.
+func alignment(sig string) int {
+	switch sig[0] {
+	case 'y', 'g', 'v':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	}
+	return 4
+}
+
+// signatureOf returns the signature of v in a variant.
+func signatureOf(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case dbusVariant:
+		return v.sig, nil
+	case byte:
+		return "y", nil
+	case bool:
+		return "b", nil
+	case int16:
+		return "n", nil
+	case uint16:
+		return "q", nil
+	case int32:
+		return "i", nil
+	case uint32:
+		return "u", nil
+	case int64:
+		return "x", nil
+	case uint64:
+		return "t", nil
+	case float64:
+		return "d", nil
+	case string:
+		return "s", nil
+	case []string:
+		return "as", nil
+	case []byte:
+		return "ay", nil
+	case map[string]interface{}:
+		return "a{sv}", nil
+	}
+	return "", fmt.Errorf("unsupported type %T in variant", v)
+}
+
+// dbusEncoder encodes values in little endian.
+type dbusEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *dbusEncoder) align(n int) {
+	for e.buf.Len()%n != 0 {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *dbusEncoder) uint32(v uint32) {
+	e.align(4)
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *dbusEncoder) uint64(v uint64) {
+	e.align(8)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	e.buf.Write(b[:])
+}
+
+// encode encodes v as the single complete type sig.
+func (e *dbusEncoder) encode(sig string, v interface{}) error {
+	mismatch := fmt.Errorf("cannot encode %T as %q", v, sig)
+	if dv, ok := v.(dbusVariant); ok && sig != "v" {
+		v = dv.value
+	}
+	switch sig[0] {
+	case 'y':
+		b, ok := v.(byte)
+		if !ok {
+			return mismatch
+		}
+		e.buf.WriteByte(b)
+	case 'b':
+		b, ok := v.(bool)
+		if !ok {
+			return mismatch
+		}
+		var u uint32
+		if b {
+			u = 1
+		}
+		e.uint32(u)
+	case 'n', 'q':
+		e.align(2)
+		var u uint16
+		switch n := v.(type) {
+		case int16:
+			u = uint16(n)
+		case uint16:
+			u = n
+		default:
+			return mismatch
+		}
+		var b [2]byte
+		binary.LittleEndian.PutUint16(b[:], u)
+		e.buf.Write(b[:])
+	case 'i', 'u':
+		switch n := v.(type) {
+		case int32:
+			e.uint32(uint32(n))
+		case uint32:
+			e.uint32(n)
+		default:
+			return mismatch
+		}
+	case 'x', 't':
+		switch n := v.(type) {
+		case int64:
+			e.uint64(uint64(n))
+		case uint64:
+			e.uint64(n)
+		default:
+			return mismatch
+		}
+	case 'd':
+		f, ok := v.(float64)
+		if !ok {
+			return mismatch
+		}
+		e.uint64(math.Float64bits(f))
+	case 's', 'o':
+		s, ok := v.(string)
+		if !ok {
+			return mismatch
+		}
+		e.uint32(uint32(len(s)))
+		e.buf.WriteString(s)
+		e.buf.WriteByte(0)
+	case 'g':
+		s, ok := v.(string)
+		if !ok || len(s) > 255 {
+			return mismatch
+		}
+		e.buf.WriteByte(byte(len(s)))
+		e.buf.WriteString(s)
+		e.buf.WriteByte(0)
+	case 'v':
+		vs, err := signatureOf(v)
+		if err != nil {
+			return err
+		}
+		if err := e.encode("g", vs); err != nil {
+			return err
+		}
+		return e.encode(vs, v)
+	case 'a':
+		return e.encodeArray(sig[1:], v)
+	case '(':
+		fields, ok := v.([]interface{})
+		types, err := splitSignature(sig[1 : len(sig)-1])
+		if !ok || err != nil || len(fields) != len(types) {
+			return mismatch
+		}
+		e.align(8)
+		for i, t := range types {
+			if err := e.encode(t, fields[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+// encodeArray encodes v as an array of elem.
+func (e *dbusEncoder) encodeArray(elem string, v interface{}) error {
+	e.uint32(0)
+	lenPos := e.buf.Len() - 4
+	e.align(alignment(elem))
+	start := e.buf.Len()
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := e.encode(elem, item); err != nil {
+				return err
+			}
+		}
+	case []string:
+		for _, item := range v {
+			if err := e.encode(elem, item); err != nil {
+				return err
+			}
+		}
+	case []byte:
+		for _, item := range v {
+			if err := e.encode(elem, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		types, err := splitSignature(elem[1 : len(elem)-1])
+		if elem[0] != '{' || err != nil || len(types) != 2 {
+			return fmt.Errorf("cannot encode %T as %q", v, "a"+elem)
+		}
+		// Keys are sorted to make the output stable.
+		var keys []string
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			e.align(8)
+			if err := e.encode(types[0], k); err != nil {
+				return err
+			}
+			if err := e.encode(types[1], v[k]); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		return fmt.Errorf("cannot encode %T as %q", v, "a"+elem)
+	}
+	binary.LittleEndian.PutUint32(e.buf.Bytes()[lenPos:], uint32(e.buf.Len()-start))
+	return nil
+}
+
+// dbusDecoder decodes values from data. Alignment is relative to the
+// beginning of data.
+type dbusDecoder struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+	// depth limits nesting of containers.
+	depth int
+}
+
+var errShortMessage = errors.New("short D-Bus message")
+
+func (d *dbusDecoder) align(n int) error {
+	d.pos = (d.pos + n - 1) / n * n
+	if d.pos > len(d.data) {
+		return errShortMessage
+	}
+	return nil
+}
+
+func (d *dbusDecoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, errShortMessage
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *dbusDecoder) uint32() (uint32, error) {
+	if err := d.align(4); err != nil {
+		return 0, err
+	}
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint32(b), nil
+}
+
+func (d *dbusDecoder) uint64() (uint64, error) {
+	if err := d.align(8); err != nil {
+		return 0, err
+	}
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint64(b), nil
+}
+
+// decode decodes a value of the single complete type sig.
+func (d *dbusDecoder) decode(sig string) (interface{}, error) {
+	switch sig[0] {
+	case 'y':
+		b, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'b':
+		u, err := d.uint32()
+		return u != 0, err
+	case 'n', 'q':
+		if err := d.align(2); err != nil {
+			return nil, err
+		}
+		b, err := d.read(2)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'n' {
+			return int16(d.order.Uint16(b)), nil
+		}
+		return d.order.Uint16(b), nil
+	case 'i':
+		u, err := d.uint32()
+		return int32(u), err
+	case 'u':
+		return d.uint32()
+	case 'x':
+		u, err := d.uint64()
+		return int64(u), err
+	case 't':
+		return d.uint64()
+	case 'd':
+		u, err := d.uint64()
+		return math.Float64frombits(u), err
+	case 's', 'o':
+		n, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(int(n) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case 'g':
+		n, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(int(n[0]) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return string(b[:n[0]]), nil
+	}
+
+	// Containers
+	if d.depth > 32 {
+		return nil, errors.New("too deep D-Bus message")
+	}
+	d.depth++
+	defer func() { d.depth-- }()
+	switch sig[0] {
+	case 'v':
+		s, err := d.decode("g")
+		if err != nil {
+			return nil, err
+		}
+		types, err := splitSignature(s.(string))
+		if err != nil || len(types) != 1 {
+			return nil, fmt.Errorf("invalid variant signature %q", s)
+		}
+		return d.decode(types[0])
+	case 'a':
+		n, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		elem := sig[1:]
+		if err := d.align(alignment(elem)); err != nil {
+			return nil, err
+		}
+		end := d.pos + int(n)
+		if end > len(d.data) {
+			return nil, errShortMessage
+		}
+		if elem[0] == '{' {
+			types, err := splitSignature(elem[1 : len(elem)-1])
+			if err != nil || len(types) != 2 || types[0] != "s" {
+				return nil, fmt.Errorf("unsupported dictionary %q", sig)
+			}
+			m := map[string]interface{}{}
+			for d.pos < end {
+				if err := d.align(8); err != nil {
+					return nil, err
+				}
+				k, err := d.decode("s")
+				if err != nil {
+					return nil, err
+				}
+				if m[k.(string)], err = d.decode(types[1]); err != nil {
+					return nil, err
+				}
+			}
+			return m, nil
+		}
+		var list []interface{}
+		for d.pos < end {
+			v, err := d.decode(elem)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case '(':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		types, err := splitSignature(sig[1 : len(sig)-1])
+		if err != nil {
+			return nil, err
+		}
+		var fields []interface{}
+		for _, t := range types {
+			v, err := d.decode(t)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, v)
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("invalid signature %q", sig)
+}
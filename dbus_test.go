@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDBusMessage_marshal(t *testing.T) {
+	m := &dbusMessage{
+		kind:        dbusMethodCall,
+		serial:      3,
+		path:        notificationsPath,
+		iface:       notificationsName,
+		member:      "Notify",
+		destination: notificationsName,
+		signature:   "susssasa{sv}i(yd)",
+		body: []interface{}{
+			"time-to-go", uint32(0), "", "tea", "tea is over.",
+			[]string{"dismiss", "Dismiss"},
+			map[string]interface{}{"urgency": byte(2), "category": "alarm", "value": int32(40)},
+			int32(-1),
+			[]interface{}{byte(1), 0.5},
+		},
+	}
+	data, err := m.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := readDBusMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.kind != m.kind || r.serial != m.serial || r.path != m.path || r.member != m.member || r.signature != m.signature {
+		t.Errorf("expected %+v to eq %+v", r, m)
+	}
+	expected := []interface{}{
+		"time-to-go", uint32(0), "", "tea", "tea is over.",
+		[]interface{}{"dismiss", "Dismiss"},
+		map[string]interface{}{"urgency": byte(2), "category": "alarm", "value": int32(40)},
+		int32(-1),
+		[]interface{}{byte(1), 0.5},
+	}
+	if !reflect.DeepEqual(r.body, expected) {
+		t.Errorf("expected %#v to eq %#v", r.body, expected)
+	}
+
+	// Truncated messages are rejected.
+	if _, err := readDBusMessage(bytes.NewReader(data[:len(data)-3])); err == nil {
+		t.Errorf("expected error for truncated message")
+	}
+}
+
+func TestSplitSignature(t *testing.T) {
+	types, err := splitSignature("susa{sv}a(yv)i")
+	if expected := []string{"s", "u", "s", "a{sv}", "a(yv)", "i"}; err != nil || !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected %q to eq %q (%v)", types, expected, err)
+	}
+	for _, sig := range []string{"a", "(si", "z"} {
+		if _, err := splitSignature(sig); err == nil {
+			t.Errorf("expected error for %q", sig)
+		}
+	}
+}
+
+// startBus starts a private session bus and points
+// DBUS_SESSION_BUS_ADDRESS to it.
+func startBus(t *testing.T) func() {
+	path, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not found")
+	}
+	cmd := exec.Command(path, "--session", "--print-address", "--nofork")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	address, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		t.Skipf("dbus-daemon failed: %v", err)
+	}
+	orig, ok := os.LookupEnv("DBUS_SESSION_BUS_ADDRESS")
+	os.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(address))
+	return func() {
+		if ok {
+			os.Setenv("DBUS_SESSION_BUS_ADDRESS", orig)
+		} else {
+			os.Unsetenv("DBUS_SESSION_BUS_ADDRESS")
+		}
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+}
+
+func TestDialBus_invalid(t *testing.T) {
+	for _, a := range []string{"", "tcp:host=localhost,port=1", "unix:path=/nonexistent/bus"} {
+		if _, err := dialBus(a); err == nil {
+			t.Errorf("expected error for %q", a)
+		}
+	}
+}
+
+func TestDialBus_silent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "time-to-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// The bus accepts the connection but never answers.
+	l, err := net.Listen("unix", filepath.Join(dir, "bus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	defer func(d time.Duration) { dbusTimeout = d }(dbusTimeout)
+	dbusTimeout = 100 * time.Millisecond
+	start := time.Now()
+	if _, err := dialBus("unix:path=" + filepath.Join(dir, "bus")); err == nil {
+		t.Errorf("expected error for the silent bus")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expected %v to be within the timeout", d)
+	}
+}
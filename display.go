@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// status is the state of the countdown of a step.
+type status struct {
+	step step
+	// no and total are the position of the step in the chain.
+	no, total int
+	// remains is the remained seconds.
+	remains int
+	// start is shifted by the time while the step is paused.
+	start  time.Time
+	paused bool
+}
+
+// pausedMark returns " (paused)" while the step is paused.
+func (s *status) pausedMark() string {
+	if s.paused {
+		return " (paused)"
+	}
+	return ""
+}
+
+// progress returns the position of the step such as " [step 2/3]",
+// or empty string for a single timer.
+func (s *status) progress() string {
+	if s.total <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" [step %d/%d]", s.no, s.total)
+}
+
+// display renders the countdown.
+type display interface {
+	// begin is called when a step starts.
+	begin(s *status)
+	// update is called every second while the step is running.
+	update(s *status)
+	// end is called when the step expires.
+	end(s *status)
+	// close restores the terminal. It may be called more than once.
+	close()
+}
+
+// liveLine writes the line updated while the step is running. On a
+// terminal it overwrites the line in place. Otherwise it writes a new
+// line every refresh seconds to keep logs readable.
+type liveLine struct {
+	w       io.Writer
+	simple  bool
+	tty     bool
+	refresh int
+}
+
+// update writes text as the current state of s.
+func (l *liveLine) update(s *status, text string) {
+	if l.simple {
+		return
+	}
+	elapsed := int(s.step.duration.Seconds()) - s.remains
+	if l.refresh > 1 && !s.paused && elapsed%l.refresh != 0 {
+		return
+	}
+	if l.tty {
+		fmt.Fprintf(l.w, "\r%s\x1b[K", text)
+	} else {
+		fmt.Fprintf(l.w, "%s\n", text)
+	}
+}
+
+// end writes text as the final state and terminates the line.
+func (l *liveLine) end(text string) {
+	if l.simple {
+		return
+	}
+	if l.tty {
+		fmt.Fprintf(l.w, "\r%s\x1b[K\n", text)
+	} else {
+		fmt.Fprintf(l.w, "%s\n", text)
+	}
+}
+
+// lineDisplay shows remained time in a line.
+type lineDisplay struct {
+	liveLine
+}
+
+func (d *lineDisplay) begin(s *status) {
+	fmt.Fprintf(d.w, "Sleeping %v%s\n", formatDuration(s.step.duration), s.step.suffix())
+}
+
+func (d *lineDisplay) update(s *status) {
+	d.liveLine.update(s, fmt.Sprintf("%s remains...%s%s%s", formatRemains(s.remains), s.step.suffix(), s.progress(), s.pausedMark()))
+}
+
+func (d *lineDisplay) end(s *status) {
+	d.liveLine.end(fmt.Sprintf("  0 sec(s) remains...%s%s", s.step.suffix(), s.progress()))
+}
+
+func (d *lineDisplay) close() {}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestLineDisplay_notTerminal(t *testing.T) {
+	var b bytes.Buffer
+	d := &lineDisplay{liveLine{w: &b, refresh: 2}}
+	s := &status{step: step{duration: 4 * time.Second}, remains: 4}
+	d.begin(s)
+	for s.remains = 3; s.remains > 0; s.remains-- {
+		d.update(s)
+	}
+	d.end(s)
+
+	expected := "Sleeping 4s\n        02s remains...\n  0 sec(s) remains...\n"
+	if b.String() != expected {
+		t.Errorf("expected %q to eq %q", b.String(), expected)
+	}
+}
+
+func TestTitleDisplay(t *testing.T) {
+	var b bytes.Buffer
+	d := newTitleDisplay(&lineDisplay{liveLine{w: ioutil.Discard, simple: true}}, &b)
+	s := &status{step: step{duration: time.Minute, label: "tea"}, remains: 42}
+	d.update(s)
+	d.end(s)
+	d.close()
+	d.close()
+
+	expected := "\x1b[22;0t" + "\x1b]0;00:42 tea - time-to-go\x07" + "\x1b]0;*** tea!!!! *** - time-to-go\x07" + "\x1b[23;0t"
+	if b.String() != expected {
+		t.Errorf("expected %q to eq %q", b.String(), expected)
+	}
+}
+
+func TestTabProgressDisplay(t *testing.T) {
+	var b bytes.Buffer
+	d := &tabProgressDisplay{display: &lineDisplay{liveLine{w: ioutil.Discard, simple: true}}, w: &b}
+	s := &status{step: step{duration: time.Minute}, remains: 15}
+	d.update(s)
+	s.paused = true
+	d.update(s)
+	d.end(s)
+	d.close()
+	d.close()
+
+	expected := "\x1b]9;4;1;75\x1b\\" + "\x1b]9;4;4;75\x1b\\" + "\x1b]9;4;2;100\x1b\\" + "\x1b]9;4;0;0\x1b\\"
+	if b.String() != expected {
+		t.Errorf("expected %q to eq %q", b.String(), expected)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"time"
+)
+
+// escalation repeats the last alarm until it is acknowledged, raising
+// the urgency and adding alarms at each repeat.
+type escalation struct {
+	// interval is the time between the end of an alarm and the repeat.
+	// Zero disables escalation.
+	interval time.Duration
+	// max is how long the alarm is repeated.
+	max time.Duration
+	// alarms are added one by one to the alarms of the step.
+	alarms []string
+}
+
+// defaultEscalationAlarms are added by escalation unless --escalate-alarm
+// is given. wall is left to --escalate-alarm since it writes to the
+// terminals of all users.
+var defaultEscalationAlarms = []string{"bell", "sound"}
+
+// urgencyLevels are the urgencies in the order of escalation.
+var urgencyLevels = []string{"low", "normal", "critical"}
+
+// raiseUrgency returns the urgency by levels higher than u, which stops
+// at critical.
+func raiseUrgency(u string, by int) string {
+	level := 1
+	for i, l := range urgencyLevels {
+		if l == u {
+			level = i
+		}
+	}
+	level += by
+	if level >= len(urgencyLevels) {
+		level = len(urgencyLevels) - 1
+	}
+	return urgencyLevels[level]
+}
+
+// escalate fires the alarm of s. When the whole timer is over, the alarm
+// is repeated with escalation of opt until ack is closed or the maximum
+// duration passes.
+func (cli *CLI) escalate(opt *options, s *status, n notice, ack <-chan struct{}) {
+	cli.alarm(opt, s, n, ack)
+	e := opt.escalation
+	if e.interval <= 0 || !n.last {
+		return
+	}
+	base := s.step.alarms
+	if base == nil {
+		base = opt.alarms
+	}
+	urgency := cli.notification.merge(s.step.notification).urgency
+	deadline := time.NewTimer(e.max)
+	defer deadline.Stop()
+	for level := 1; ; level++ {
+		select {
+		case <-ack:
+			return
+		case <-deadline.C:
+			return
+		case <-time.After(e.interval):
+		}
+		st := *s
+		st.step.alarms = append([]string(nil), base...)
+		for i := 0; i < level && i < len(e.alarms); i++ {
+			if !isOneOf(e.alarms[i], st.step.alarms) {
+				st.step.alarms = append(st.step.alarms, e.alarms[i])
+			}
+		}
+		st.step.notification.urgency = raiseUrgency(urgency, level)
+		cli.alarm(opt, &st, n, ack)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRaiseUrgency(t *testing.T) {
+	cases := []struct {
+		u        string
+		by       int
+		expected string
+	}{
+		{"low", 1, "normal"},
+		{"", 1, "critical"},
+		{"normal", 5, "critical"},
+		{"critical", 1, "critical"},
+	}
+	for _, c := range cases {
+		if u := raiseUrgency(c.u, c.by); u != c.expected {
+			t.Errorf("expected %q to eq %q", u, c.expected)
+		}
+	}
+}
+
+func TestCLI_escalate(t *testing.T) {
+	var out, errs, events bytes.Buffer
+	cli := &CLI{outStream: &out, errStream: &errs, command: "true"}
+	opt := &options{
+		alarms:     []string{"command"},
+		events:     newEventWriter(&events),
+		escalation: escalation{interval: 20 * time.Millisecond, max: time.Second, alarms: []string{"print", "command"}},
+	}
+	s := &status{step: step{duration: time.Minute, label: "tea"}}
+
+	ack := make(chan struct{})
+	time.AfterFunc(100*time.Millisecond, func() {
+		close(ack)
+	})
+	cli.escalate(opt, s, notice{summary: "time-to-go", body: "tea is over.", last: true}, ack)
+
+	var fired [][]string
+	dec := json.NewDecoder(&events)
+	for dec.More() {
+		var ev event
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+		fired = append(fired, ev.Alarms)
+	}
+	if len(fired) < 3 {
+		t.Fatalf("expected %q to be repeated", fired)
+	}
+	// The alarms are added one by one without duplicates.
+	expected := [][]string{{"command"}, {"command", "print"}, {"command", "print"}}
+	if !reflect.DeepEqual(fired[:3], expected) {
+		t.Errorf("expected %q to eq %q", fired[:3], expected)
+	}
+
+	// The alarm is not repeated after the maximum duration.
+	events.Reset()
+	opt.escalation.interval, opt.escalation.max = 50*time.Millisecond, 75*time.Millisecond
+	cli.escalate(opt, s, notice{summary: "time-to-go", body: "tea is over.", last: true}, nil)
+	if n := bytes.Count(events.Bytes(), []byte("\n")); n != 2 {
+		t.Errorf("expected %d to eq 2", n)
+	}
+}
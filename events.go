@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// event is a line of the JSON-lines stream given by --output json.
+type event struct {
+	// Event is one of started, tick, warning, paused, resumed,
+	// cancelled, fired, alarm and snoozed.
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Label string    `json:"label,omitempty"`
+	Step  int       `json:"step,omitempty"`
+	Steps int       `json:"steps,omitempty"`
+	// Remaining and Total are in seconds.
+	Remaining int `json:"remaining"`
+	Total     int `json:"total"`
+	// Level is "yellow" or "red" for warning.
+	Level string `json:"level,omitempty"`
+	// Alarms are the alarms which fired for alarm.
+	Alarms []string `json:"alarms,omitempty"`
+}
+
+// eventWriter writes events as JSON lines. Nil eventWriter discards
+// events so that callers don't need to check --output.
+type eventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	return &eventWriter{enc: json.NewEncoder(w)}
+}
+
+// emit writes the event named kind for s.
+func (e *eventWriter) emit(kind string, s *status) {
+	e.emitLevel(kind, s, "")
+}
+
+// emitLevel writes the event with level.
+func (e *eventWriter) emitLevel(kind string, s *status, level string) {
+	if e == nil {
+		return
+	}
+	ev := newEvent(kind, s)
+	ev.Level = level
+	e.write(ev)
+}
+
+// emitAlarm writes the alarm event telling the alarms which fired.
+func (e *eventWriter) emitAlarm(s *status, alarms []string) {
+	if e == nil {
+		return
+	}
+	ev := newEvent("alarm", s)
+	ev.Alarms = alarms
+	e.write(ev)
+}
+
+func newEvent(kind string, s *status) event {
+	ev := event{
+		Event:     kind,
+		Time:      time.Now(),
+		Label:     s.step.label,
+		Remaining: s.remains,
+		Total:     int(s.step.duration.Seconds()),
+	}
+	if s.total > 1 {
+		ev.Step, ev.Steps = s.no, s.total
+	}
+	if ev.Remaining < 0 {
+		ev.Remaining = 0
+	}
+	return ev
+}
+
+func (e *eventWriter) write(ev event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.enc.Encode(ev)
+}
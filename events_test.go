@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEventWriter_emit(t *testing.T) {
+	var b bytes.Buffer
+	e := newEventWriter(&b)
+	s := &status{step: step{duration: time.Minute, label: "tea"}, no: 2, total: 3, remains: 42}
+	e.emit("tick", s)
+	e.emitLevel("warning", s, "red")
+
+	var evs []event
+	dec := json.NewDecoder(&b)
+	for dec.More() {
+		var ev event
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatalf("Decode returned error: %v", err)
+		}
+		evs = append(evs, ev)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("expected %d events to eq %d", len(evs), 2)
+	}
+	if ev := evs[0]; ev.Event != "tick" || ev.Label != "tea" || ev.Step != 2 || ev.Steps != 3 || ev.Remaining != 42 || ev.Total != 60 {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if evs[1].Level != "red" {
+		t.Errorf("expected %q to eq %q", evs[1].Level, "red")
+	}
+
+	// nil eventWriter discards events.
+	var nilWriter *eventWriter
+	nilWriter.emit("tick", s)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Styles of flashScreen.
+const (
+	// flashReverse toggles reverse video with DECSCNM.
+	flashReverse = "reverse"
+	// flashBackground changes the background color with OSC 11 for the
+	// terminals which don't support DECSCNM.
+	flashBackground = "background"
+)
+
+// detectMultiplexers returns the terminal multiplexers between
+// time-to-go and the terminal, from the innermost one.
+func detectMultiplexers() []string {
+	_, tmux := os.LookupEnv("TMUX")
+	_, screen := os.LookupEnv("STY")
+	term := os.Getenv("TERM")
+	switch {
+	case tmux && screen:
+		// Both variables are inherited by nested multiplexers. Assume
+		// tmux inside screen, which can be overridden by --multiplexer.
+		return []string{"tmux", "screen"}
+	case tmux:
+		return []string{"tmux"}
+	case screen:
+		return []string{"screen"}
+	// Environment variables are not passed over SSH, but TERM is. TERM
+	// of screen is not trusted since it is the default of tmux too.
+	case strings.HasPrefix(term, "tmux"):
+		return []string{"tmux"}
+	}
+	return nil
+}
+
+// passthrough writes escape sequences to w passing them through the
+// terminal multiplexers to the outer terminal. Each write must be a whole
+// sequence.
+type passthrough struct {
+	w io.Writer
+	// layers are "tmux" or "screen" from the innermost one.
+	layers []string
+}
+
+func (p passthrough) Write(b []byte) (int, error) {
+	if len(p.layers) == 0 {
+		return p.w.Write(b)
+	}
+	if _, err := io.WriteString(p.w, wrapSequence(string(b), p.layers)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// wrapSequence wraps seq for layers of multiplexers given from the
+// innermost one. The sequence for the outermost one is wrapped first so
+// that each multiplexer unwraps its own layer.
+func wrapSequence(seq string, layers []string) string {
+	for i := len(layers) - 1; i >= 0; i-- {
+		switch layers[i] {
+		case "tmux":
+			seq = "\x1bPtmux;" + strings.Replace(seq, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+		case "screen":
+			// ST inside would terminate DCS of screen, so OSC is
+			// terminated with BEL instead. screen limits the length of
+			// DCS, so seq is split into chunks.
+			if strings.HasPrefix(seq, "\x1b]") {
+				seq = strings.Replace(seq, "\x1b\\", "\x07", -1)
+			}
+			var b strings.Builder
+			for len(seq) > 0 {
+				n := 76
+				if n > len(seq) {
+					n = len(seq)
+				}
+				// ESC at the end of the chunk would be taken with
+				// the following ST.
+				if seq[n-1] == '\x1b' {
+					n--
+				}
+				b.WriteString("\x1bP" + seq[:n] + "\x1b\\")
+				seq = seq[n:]
+			}
+			seq = b.String()
+		}
+	}
+	return seq
+}
+
+// parseMultiplexers converts --multiplexer to the layers of passthrough.
+// It returns nil for auto.
+func parseMultiplexers(s string) ([]string, error) {
+	switch s {
+	case "auto":
+		return nil, nil
+	case "none":
+		return []string{}, nil
+	}
+	layers := strings.Split(s, ",")
+	for _, l := range layers {
+		if l != "tmux" && l != "screen" {
+			return nil, fmt.Errorf("Unknown multiplexer: %s", l)
+		}
+	}
+	return layers, nil
+}
+
+// flashStyle decides the style of flashScreen. auto probes the terminal
+// connected to w and falls back to flashBackground when it tells that
+// DECSCNM is not supported. The probe is skipped under multiplexers
+// since they answer on behalf of the terminal.
+func flashStyle(style string, w io.Writer, layers []string) string {
+	if style != "auto" {
+		return style
+	}
+	if len(layers) > 0 || !isTerminal(w) {
+		return flashReverse
+	}
+	if supported, ok := probeMode(5); ok && !supported {
+		return flashBackground
+	}
+	return flashReverse
+}
+
+// flashConfig is the pattern of flashScreen given by command line flags.
+type flashConfig struct {
+	style   string
+	pattern string
+	// count is the number of times the pattern is repeated.
+	count int
+	// on and off are the base durations of a flash and the following
+	// normal video.
+	on, off time.Duration
+	// untilAck repeats the pattern until it is stopped.
+	untilAck bool
+}
+
+// flashPatterns are the defaults of the patterns of flashScreen.
+var flashPatterns = map[string]flashConfig{
+	// blink flashes at regular intervals.
+	"blink": {count: 6, on: 500 * time.Millisecond, off: 500 * time.Millisecond},
+	// sos flashes SOS in Morse code.
+	"sos": {count: 1, on: 200 * time.Millisecond, off: 200 * time.Millisecond},
+	// pulse flashes faster and faster.
+	"pulse": {count: 2, on: 400 * time.Millisecond, off: 400 * time.Millisecond},
+}
+
+// parseFlashConfig makes flashConfig from --flash and its related flags.
+// count, on and off may be zero or empty for the default of the pattern.
+func parseFlashConfig(pattern string, count int, on, off string) (flashConfig, error) {
+	c, ok := flashPatterns[pattern]
+	if !ok {
+		return c, fmt.Errorf("Unknown flash pattern: %s", pattern)
+	}
+	c.pattern = pattern
+	if count < 0 {
+		return c, fmt.Errorf("Invalid flash count: %d", count)
+	}
+	if count > 0 {
+		c.count = count
+	}
+	for _, f := range []struct {
+		s string
+		d *time.Duration
+	}{{on, &c.on}, {off, &c.off}} {
+		if f.s == "" {
+			continue
+		}
+		d, err := parseInterval(f.s)
+		if err != nil || d <= 0 {
+			return c, fmt.Errorf("Invalid flash interval: %s", f.s)
+		}
+		*f.d = d
+	}
+	return c, nil
+}
+
+// flashBeat is a flash and the following normal video.
+type flashBeat struct {
+	on, off time.Duration
+}
+
+// beats returns a round of the pattern.
+func (c flashConfig) beats() []flashBeat {
+	switch c.pattern {
+	case "sos":
+		// A dash is three times longer than a dot. Letters are separated
+		// by three times longer gap and words by seven times.
+		var beats []flashBeat
+		for i, n := range []time.Duration{1, 1, 1, 3, 3, 3, 1, 1, 1} {
+			gap := c.off
+			if i%3 == 2 {
+				gap = 3 * c.off
+			}
+			beats = append(beats, flashBeat{n * c.on, gap})
+		}
+		beats[len(beats)-1].off = 7 * c.off
+		return beats
+	case "pulse":
+		// Each flash is a quarter shorter than the previous one.
+		var beats []flashBeat
+		on, off := c.on, c.off
+		for i := 0; i < 8; i++ {
+			beats = append(beats, flashBeat{on, off})
+			on, off = on*3/4, off*3/4
+		}
+		beats[len(beats)-1].off = 2 * c.off
+		return beats
+	}
+	return []flashBeat{{c.on, c.off}}
+}
+
+// flashScreen makes current terminal screen flashing with the pattern c
+// using ANSI escape sequences. It stops flashing when stop is closed, and
+// the screen is always left in normal video. It has the potential not to
+// work in some terminal environments.
+func flashScreen(w io.Writer, c flashConfig, stop <-chan struct{}) {
+	on, off := "\x1b[?5h", "\x1b[?5l"
+	if c.style == flashBackground {
+		// OSC 111 resets the background to the default.
+		on, off = "\x1b]11;#ffffff\x1b\\", "\x1b]111\x1b\\"
+	}
+	lit := false
+	defer func() {
+		if lit {
+			fmt.Fprint(w, off)
+		}
+	}()
+
+	beats := c.beats()
+	for i := 0; c.untilAck || i < c.count; i++ {
+		for j, b := range beats {
+			// reverse video
+			fmt.Fprint(w, on)
+			lit = true
+			if !sleep(b.on, stop) {
+				return
+			}
+			// normal video
+			fmt.Fprint(w, off)
+			lit = false
+			if !c.untilAck && i == c.count-1 && j == len(beats)-1 {
+				return
+			}
+			if !sleep(b.off, stop) {
+				return
+			}
+		}
+	}
+}
+
+// sleep pauses for d. It returns false when stop is closed before d
+// passes.
+func sleep(d time.Duration, stop <-chan struct{}) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
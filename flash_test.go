@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrapSequence(t *testing.T) {
+	cases := []struct {
+		layers   []string
+		seq      string
+		expected string
+	}{
+		{nil, "\x1b[?5h", "\x1b[?5h"},
+		{[]string{"tmux"}, "\x1b[?5h", "\x1bPtmux;\x1b\x1b[?5h\x1b\\"},
+		{[]string{"screen"}, "\x1b[?5h", "\x1bP\x1b[?5h\x1b\\"},
+		{[]string{"screen"}, "\x1b]9;hi\x1b\\", "\x1bP\x1b]9;hi\x07\x1b\\"},
+		// tmux inside screen: the layer of screen is wrapped by tmux.
+		{[]string{"tmux", "screen"}, "\x1b[?5h", "\x1bPtmux;\x1b\x1bP\x1b\x1b[?5h\x1b\x1b\\\x1b\\"},
+		// The long sequence is split into chunks before ESC.
+		{[]string{"screen"}, strings.Repeat("a", 75) + "\x1b[?5h", "\x1bP" + strings.Repeat("a", 75) + "\x1b\\\x1bP\x1b[?5h\x1b\\"},
+	}
+	for _, c := range cases {
+		if s := wrapSequence(c.seq, c.layers); s != c.expected {
+			t.Errorf("expected %q to eq %q", s, c.expected)
+		}
+	}
+}
+
+func TestDetectMultiplexers(t *testing.T) {
+	for _, k := range []string{"TMUX", "STY", "TERM"} {
+		if v, ok := os.LookupEnv(k); ok {
+			defer os.Setenv(k, v)
+		} else {
+			defer os.Unsetenv(k)
+		}
+		os.Unsetenv(k)
+	}
+	cases := map[string][]string{
+		"tmux-256color": {"tmux"},
+		// TERM of tmux is screen by default, which is not trusted.
+		"screen":         nil,
+		"xterm-256color": nil,
+	}
+	for term, expected := range cases {
+		os.Setenv("TERM", term)
+		if layers := detectMultiplexers(); !reflect.DeepEqual(layers, expected) {
+			t.Errorf("%s: expected %q to eq %q", term, layers, expected)
+		}
+	}
+	os.Setenv("STY", "1.pts-0")
+	if layers := detectMultiplexers(); !reflect.DeepEqual(layers, []string{"screen"}) {
+		t.Errorf("expected %q to eq %q", layers, []string{"screen"})
+	}
+}
+
+func TestParseMultiplexers(t *testing.T) {
+	cases := map[string][]string{
+		"auto":        nil,
+		"none":        {},
+		"tmux,screen": {"tmux", "screen"},
+	}
+	for s, expected := range cases {
+		layers, err := parseMultiplexers(s)
+		if err != nil || !reflect.DeepEqual(layers, expected) {
+			t.Errorf("expected %q to eq %q (%v)", layers, expected, err)
+		}
+	}
+	if _, err := parseMultiplexers("byobu"); err == nil {
+		t.Errorf("expected error for unknown multiplexer")
+	}
+}
+
+func TestParseFlashConfig(t *testing.T) {
+	c, err := parseFlashConfig("sos", 0, "", "100ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.count != 1 || c.on != 200*time.Millisecond || c.off != 100*time.Millisecond {
+		t.Errorf("expected %+v to have the defaults of sos with off 100ms", c)
+	}
+	for _, args := range []struct {
+		pattern string
+		count   int
+		on      string
+	}{{"morse", 0, ""}, {"blink", -1, ""}, {"blink", 0, "0s"}, {"blink", 0, "fast"}} {
+		if _, err := parseFlashConfig(args.pattern, args.count, args.on, ""); err == nil {
+			t.Errorf("expected error for %+v", args)
+		}
+	}
+}
+
+func TestFlashBeats(t *testing.T) {
+	ms := time.Millisecond
+	c := flashConfig{pattern: "sos", on: ms, off: ms}
+	beats := c.beats()
+	if len(beats) != 9 || beats[0].on != ms || beats[3].on != 3*ms || beats[2].off != 3*ms || beats[8].off != 7*ms {
+		t.Errorf("expected %v to be SOS", beats)
+	}
+	c = flashConfig{pattern: "pulse", on: 100 * ms, off: 100 * ms}
+	beats = c.beats()
+	for i := 1; i < len(beats); i++ {
+		if beats[i].on >= beats[i-1].on {
+			t.Errorf("expected %v to accelerate", beats)
+		}
+	}
+}
+
+func TestFlashScreen(t *testing.T) {
+	var b bytes.Buffer
+	c := flashConfig{pattern: "blink", count: 2, on: time.Millisecond, off: time.Millisecond}
+	flashScreen(&b, c, nil)
+	if expected := strings.Repeat("\x1b[?5h\x1b[?5l", 2); b.String() != expected {
+		t.Errorf("expected %q to eq %q", b.String(), expected)
+	}
+
+	// It flashes until stopped, leaving the screen in normal video.
+	b.Reset()
+	c = flashConfig{style: flashBackground, pattern: "sos", on: 5 * time.Millisecond, off: time.Millisecond, untilAck: true}
+	stop := make(chan struct{})
+	time.AfterFunc(20*time.Millisecond, func() { close(stop) })
+	flashScreen(&b, c, stop)
+	if s := b.String(); !strings.HasSuffix(s, "\x1b]111\x1b\\") || strings.Count(s, "\x1b]11;") != strings.Count(s, "\x1b]111") {
+		t.Errorf("expected %q to end in normal video", s)
+	}
+}
+
+func TestOptions_flashes(t *testing.T) {
+	// The default alarms flash the screen on a terminal.
+	cli := &CLI{tty: true}
+	if opt := (&options{alarms: cli.defaultAlarms()}); !opt.flashes(nil) {
+		t.Errorf("expected %q to flash", opt.alarms)
+	}
+	cli.tty = false
+	if opt := (&options{alarms: cli.defaultAlarms()}); opt.flashes(nil) {
+		t.Errorf("expected %q not to flash", opt.alarms)
+	}
+
+	cases := []struct {
+		opt      options
+		steps    []step
+		expected bool
+	}{
+		{options{alarms: []string{"bell"}}, nil, false},
+		{options{alarms: []string{"bell"}, interval: 60, chime: "flash"}, nil, true},
+		{options{alarms: []string{"bell"}, escalation: escalation{alarms: []string{"flash"}}}, nil, true},
+		{options{alarms: []string{"bell"}}, []step{{parallel: []step{{alarms: []string{"flash"}}}}}, true},
+	}
+	for _, c := range cases {
+		if f := c.opt.flashes(c.steps); f != c.expected {
+			t.Errorf("%+v: expected %v to eq %v", c.opt, f, c.expected)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// formatData is the values available in the template of --format.
+type formatData struct {
+	// State is "started", "running" or "finished".
+	State  string
+	Paused bool
+	Label  string
+	// Step and Steps are the position of the step in the chain.
+	Step, Steps               int
+	Remaining, Elapsed, Total time.Duration
+	Percent                   int
+	Start, End                time.Time
+	Hours, Minutes, Seconds   int
+}
+
+// newFormatData returns formatData of s.
+func newFormatData(s *status, state string) formatData {
+	rem := s.remains
+	if rem < 0 {
+		rem = 0
+	}
+	total := s.step.duration
+	elapsed := total - time.Duration(rem)*time.Second
+	percent := 100
+	if total > 0 {
+		percent = int(elapsed * 100 / total)
+	}
+	return formatData{
+		State:     state,
+		Paused:    s.paused,
+		Label:     s.step.label,
+		Step:      s.no,
+		Steps:     s.total,
+		Remaining: time.Duration(rem) * time.Second,
+		Elapsed:   elapsed,
+		Total:     total,
+		Percent:   percent,
+		Start:     s.start,
+		End:       s.start.Add(total),
+		Hours:     rem / 3600,
+		Minutes:   rem / 60 % 60,
+		Seconds:   rem % 60,
+	}
+}
+
+// formatFuncs are the helpers available in the template of --format.
+var formatFuncs = template.FuncMap{
+	// pad pads v to width on the left, with "0" for numbers.
+	"pad": func(width int, v interface{}) string {
+		if n, ok := v.(int); ok {
+			return fmt.Sprintf("%0*d", width, n)
+		}
+		return fmt.Sprintf("%*v", width, v)
+	},
+	// rpad pads v to width with spaces on the right.
+	"rpad": func(width int, v interface{}) string {
+		return fmt.Sprintf("%-*v", width, v)
+	},
+	"clock": func(d time.Duration) string {
+		return clock(int(d.Seconds()))
+	},
+	"humanize": humanize,
+}
+
+// parseFormat parses the template of --format and checks it with a
+// sample status.
+func parseFormat(s string) (*template.Template, error) {
+	t, err := template.New("format").Funcs(formatFuncs).Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	sample := &status{step: step{duration: time.Minute}, no: 1, total: 1, remains: 60, start: time.Now()}
+	if err := t.Execute(ioutil.Discard, newFormatData(sample, "started")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// humanize formats d such as "1 hour 2 minutes 5 seconds".
+func humanize(d time.Duration) string {
+	sec := int(d.Seconds())
+	var parts []string
+	for _, u := range []struct {
+		sec  int
+		name string
+	}{{3600, "hour"}, {60, "minute"}, {1, "second"}} {
+		n := sec / u.sec
+		sec %= u.sec
+		switch {
+		case n == 1:
+			parts = append(parts, "1 "+u.name)
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%d %ss", n, u.name))
+		}
+	}
+	if len(parts) == 0 {
+		return "0 seconds"
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatDisplay renders the countdown with the template of --format.
+// The template is used for the banner, every update and the final
+// message, which are distinguished by .State.
+type formatDisplay struct {
+	liveLine
+	tmpl *template.Template
+}
+
+func (d *formatDisplay) render(s *status, state string) string {
+	var b bytes.Buffer
+	d.tmpl.Execute(&b, newFormatData(s, state))
+	return b.String()
+}
+
+func (d *formatDisplay) begin(s *status) {
+	fmt.Fprintf(d.w, "%s\n", d.render(s, "started"))
+}
+
+func (d *formatDisplay) update(s *status) {
+	d.liveLine.update(s, d.render(s, "running"))
+}
+
+func (d *formatDisplay) end(s *status) {
+	if d.simple {
+		// The final message is shown even in simple output.
+		fmt.Fprintf(d.w, "%s\n", d.render(s, "finished"))
+		return
+	}
+	d.liveLine.end(d.render(s, "finished"))
+}
+
+func (d *formatDisplay) close() {}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestParseFormat(t *testing.T) {
+	tmpl, err := parseFormat(`{{.Label}} {{pad 2 .Minutes}}:{{pad 2 .Seconds}} {{.Percent}}% {{clock .Total}} {{humanize .Remaining}} [{{rpad 4 .State}}]`)
+	if err != nil {
+		t.Fatalf("parseFormat returned error: %v", err)
+	}
+	s := &status{step: step{duration: 5 * time.Minute, label: "tea"}, no: 1, total: 1, remains: 185, start: time.Now()}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, newFormatData(s, "running")); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	expected := "tea 03:05 38% 05:00 3 minutes 5 seconds [running]"
+	if b.String() != expected {
+		t.Errorf("expected %q to eq %q", b.String(), expected)
+	}
+
+	if _, err := parseFormat("{{.Unknown}}"); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	cases := map[time.Duration]string{
+		0:                           "0 seconds",
+		time.Second:                 "1 second",
+		time.Hour + 2*time.Minute:   "1 hour 2 minutes",
+		2*time.Hour + 5*time.Second: "2 hours 5 seconds",
+	}
+	for d, expected := range cases {
+		if s := humanize(d); s != expected {
+			t.Errorf("expected %q to eq %q", s, expected)
+		}
+	}
+}
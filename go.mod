@@ -3,6 +3,8 @@ module github.com/tdsh/time-to-go
 go 1.12
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/mattn/go-gtk v0.0.0-20190405072524-4deadb416788
 	github.com/mqu/go-notify v0.0.0-20130719194048-ef6f6f49d093
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,64 @@
+//go:build libnotify
+// +build libnotify
+
+package main
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/mqu/go-notify"
+)
+
+// libnotify needs cgo, so it is built only with -tags libnotify.
+func init() {
+	alarmBackends["libnotify"] = func(cli *CLI) Alarm {
+		return libnotifyAlarm{}
+	}
+}
+
+var (
+	libnotifyInit sync.Once
+	// libnotifyErr tells why libnotify cannot show notification.
+	libnotifyErr error
+)
+
+// libnotifyAlarm shows desktop notification with libnotify.
+type libnotifyAlarm struct{}
+
+func (libnotifyAlarm) Fire(n notice, ack <-chan struct{}) error {
+	libnotifyInit.Do(func() {
+		var name, vendor, version, spec string
+		switch {
+		case !notify.Init("time-to-go"):
+			libnotifyErr = errors.New("libnotify is not initialized")
+		case !notify.GetServerInfo(&name, &vendor, &version, &spec):
+			libnotifyErr = errors.New("no notification server")
+		}
+	})
+	if libnotifyErr != nil {
+		return libnotifyErr
+	}
+	nn := notify.NotificationNew(n.summary, n.body, "appointment-soon")
+	if nn == nil {
+		return errors.New("failed to create notification")
+	}
+	hints := n.style.hints()
+	nn.SetUrgency(notify.NotifyUrgency(hints["urgency"].(byte)))
+	if n.style.category != "" {
+		nn.SetCategory(n.style.category)
+	}
+	for _, key := range []string{"image-path", "sound-file", "sound-name"} {
+		if v, ok := hints[key].(string); ok {
+			nn.SetHintString(key, v)
+		}
+	}
+	// go-notify cannot set boolean hints, so resident and transient are
+	// shown only through D-Bus.
+	nn.SetTimeout(n.style.timeout())
+	// The error is never nil but empty on success.
+	if err := nn.Show(); err.Message() != "" {
+		return err
+	}
+	return nil
+}
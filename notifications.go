@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The notification server of the desktop.
+const (
+	notificationsName = "org.freedesktop.Notifications"
+	notificationsPath = "/org/freedesktop/Notifications"
+)
+
+// notificationsClient calls org.freedesktop.Notifications over the
+// session bus.
+type notificationsClient struct {
+	conn *dbusConn
+	// caps are the capabilities of the server.
+	caps []string
+
+	mu sync.Mutex
+	// waiters receive the action invoked on the notifications, or ""
+	// when the notification is closed.
+	waiters map[uint32]chan string
+	// progress is the id of the progress notification handed over to
+	// the notification of the alarm.
+	progress uint32
+}
+
+// dialNotifications connects to the notification server on the session
+// bus. It fails unless the server answers, since the bus may be running
+// without the server on a headless machine.
+func dialNotifications() (*notificationsClient, error) {
+	conn, err := dialSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	// Receive ActionInvoked and NotificationClosed.
+	rule := "type='signal',interface='" + notificationsName + "',path='" + notificationsPath + "'"
+	if _, err := conn.call(busName, busPath, busName, "AddMatch", "s", rule); err != nil {
+		conn.close()
+		return nil, err
+	}
+	n := &notificationsClient{conn: conn, waiters: map[uint32]chan string{}}
+	go n.dispatch()
+	if _, err := n.serverInfo(); err != nil {
+		conn.close()
+		return nil, fmt.Errorf("no notification server: %v", err)
+	}
+	if n.caps, err = n.capabilities(); err != nil {
+		conn.close()
+		return nil, err
+	}
+	return n, nil
+}
+
+// close closes the connection to the server.
+func (n *notificationsClient) close() error {
+	return n.conn.close()
+}
+
+// notify shows the notification and returns its id. The notification
+// replaces the one of replaces unless it is zero. actions are pairs of
+// the key and the label of buttons. timeout is in milliseconds, where -1
+// is the default of the server and 0 never expires.
+func (n *notificationsClient) notify(replaces uint32, icon, summary, body string, actions []string, hints map[string]interface{}, timeout int32) (uint32, error) {
+	if hints == nil {
+		hints = map[string]interface{}{}
+	}
+	reply, err := n.conn.call(notificationsName, notificationsPath, notificationsName, "Notify", "susssasa{sv}i",
+		"time-to-go", replaces, icon, summary, body, actions, hints, timeout)
+	if err != nil {
+		return 0, err
+	}
+	if len(reply) == 0 {
+		return 0, errors.New("no notification id is returned")
+	}
+	id, _ := reply[0].(uint32)
+	return id, nil
+}
+
+// closeNotification closes the notification of id.
+func (n *notificationsClient) closeNotification(id uint32) error {
+	_, err := n.conn.call(notificationsName, notificationsPath, notificationsName, "CloseNotification", "u", id)
+	return err
+}
+
+// capabilities returns the optional features supported by the server
+// such as "actions" and "body-markup".
+func (n *notificationsClient) capabilities() ([]string, error) {
+	reply, err := n.conn.call(notificationsName, notificationsPath, notificationsName, "GetCapabilities", "")
+	if err != nil {
+		return nil, err
+	}
+	var caps []string
+	if len(reply) > 0 {
+		list, _ := reply[0].([]interface{})
+		for _, c := range list {
+			if s, ok := c.(string); ok {
+				caps = append(caps, s)
+			}
+		}
+	}
+	return caps, nil
+}
+
+// serverInfo returns the name and the version of the server.
+func (n *notificationsClient) serverInfo() (string, error) {
+	reply, err := n.conn.call(notificationsName, notificationsPath, notificationsName, "GetServerInfo", "")
+	if err != nil {
+		return "", err
+	}
+	if len(reply) < 3 {
+		return "", errors.New("invalid server information")
+	}
+	return fmt.Sprintf("%v %v", reply[0], reply[2]), nil
+}
+
+// markupEscaper escapes the characters of body markup.
+var markupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// text returns s for the body, which is escaped if the server interprets
+// markup.
+func (n *notificationsClient) text(s string) string {
+	if n.supports("body-markup") {
+		return markupEscaper.Replace(s)
+	}
+	return s
+}
+
+// bold returns s for the body, which is emphasized if the server
+// supports markup.
+func (n *notificationsClient) bold(s string) string {
+	if n.supports("body-markup") {
+		return "<b>" + markupEscaper.Replace(s) + "</b>"
+	}
+	return s
+}
+
+// handOver makes the notification of id replaced by the next alarm. The
+// notification handed over before is closed if it is left.
+func (n *notificationsClient) handOver(id uint32) {
+	if prev := n.takeOver(); prev != 0 && prev != id {
+		n.closeNotification(prev)
+	}
+	n.mu.Lock()
+	n.progress = id
+	n.mu.Unlock()
+}
+
+// takeOver returns the id of the notification handed over, or 0.
+func (n *notificationsClient) takeOver() uint32 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	id := n.progress
+	n.progress = 0
+	return id
+}
+
+// supports reports whether the server has the capability.
+func (n *notificationsClient) supports(capability string) bool {
+	return isOneOf(capability, n.caps)
+}
+
+// actionInvoked waits for the action invoked on the notification of id
+// and returns its key. It returns "" when the notification is closed,
+// done is closed, timeout passes or the connection is lost. Zero timeout
+// waits forever.
+func (n *notificationsClient) actionInvoked(id uint32, done <-chan struct{}, timeout time.Duration) string {
+	ch := make(chan string, 1)
+	n.mu.Lock()
+	n.waiters[id] = ch
+	n.mu.Unlock()
+	defer func() {
+		n.mu.Lock()
+		delete(n.waiters, id)
+		n.mu.Unlock()
+	}()
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case key := <-ch:
+		return key
+	case <-done:
+	case <-expired:
+	case <-n.conn.done:
+	}
+	return ""
+}
+
+// dispatch relays ActionInvoked and NotificationClosed to the waiters.
+func (n *notificationsClient) dispatch() {
+	for {
+		var m *dbusMessage
+		select {
+		case m = <-n.conn.incoming:
+		case <-n.conn.done:
+			return
+		}
+		if m.kind != dbusSignal || m.iface != notificationsName || len(m.body) < 2 {
+			continue
+		}
+		id, _ := m.body[0].(uint32)
+		key := ""
+		switch m.member {
+		case "ActionInvoked":
+			key, _ = m.body[1].(string)
+		case "NotificationClosed":
+		default:
+			continue
+		}
+		n.mu.Lock()
+		if ch := n.waiters[id]; ch != nil {
+			select {
+			case ch <- key:
+			default:
+			}
+		}
+		n.mu.Unlock()
+	}
+}
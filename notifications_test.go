@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubServer is a notification server which records Notify calls.
+type stubServer struct {
+	conn     *dbusConn
+	caps     []string
+	notified chan []interface{}
+	// choices are invoked one by one on the notifications with actions.
+	choices chan string
+}
+
+func newStubServer(t *testing.T, caps []string) *stubServer {
+	conn, err := dialSessionBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// DBUS_NAME_FLAG_DO_NOT_QUEUE
+	if _, err := conn.call(busName, busPath, busName, "RequestName", "su", notificationsName, uint32(4)); err != nil {
+		t.Fatal(err)
+	}
+	s := &stubServer{conn: conn, caps: caps, notified: make(chan []interface{}, 16), choices: make(chan string, 16)}
+	go s.serve()
+	return s
+}
+
+func (s *stubServer) serve() {
+	var id uint32
+	for {
+		var m *dbusMessage
+		select {
+		case m = <-s.conn.incoming:
+		case <-s.conn.done:
+			return
+		}
+		if m.kind != dbusMethodCall || m.iface != notificationsName {
+			continue
+		}
+		reply := &dbusMessage{kind: dbusMethodReturn, replySerial: m.serial, destination: m.sender}
+		switch m.member {
+		case "Notify":
+			id++
+			if replaces := m.body[1].(uint32); replaces != 0 {
+				id = replaces
+			}
+			reply.signature, reply.body = "u", []interface{}{id}
+			s.notified <- m.body
+			if actions, _ := m.body[5].([]interface{}); len(actions) > 0 {
+				select {
+				case key := <-s.choices:
+					// The user clicks the button after a while.
+					id := id
+					time.AfterFunc(100*time.Millisecond, func() {
+						s.emit("ActionInvoked", "us", id, key)
+					})
+				default:
+				}
+			}
+		case "GetCapabilities":
+			reply.signature, reply.body = "as", []interface{}{s.caps}
+		case "GetServerInfo":
+			reply.signature, reply.body = "ssss", []interface{}{"stub", "time-to-go", "1.0", "1.2"}
+		case "CloseNotification":
+			s.emit("NotificationClosed", "uu", m.body[0], uint32(3))
+		default:
+			reply = &dbusMessage{kind: dbusError, replySerial: m.serial, destination: m.sender, errorName: "org.freedesktop.DBus.Error.UnknownMethod"}
+		}
+		s.conn.send(reply)
+	}
+}
+
+func (s *stubServer) emit(member, sig string, args ...interface{}) {
+	s.conn.send(&dbusMessage{kind: dbusSignal, path: notificationsPath, iface: notificationsName, member: member, signature: sig, body: args})
+}
+
+func TestNotificationsClient(t *testing.T) {
+	defer startBus(t)()
+	server := newStubServer(t, []string{"actions", "body"})
+	defer server.conn.close()
+
+	n, err := dialNotifications()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.close()
+
+	caps, err := n.capabilities()
+	if expected := []string{"actions", "body"}; err != nil || !reflect.DeepEqual(caps, expected) {
+		t.Errorf("expected %q to eq %q (%v)", caps, expected, err)
+	}
+	if info, err := n.serverInfo(); err != nil || info != "stub 1.0" {
+		t.Errorf("expected %q to eq %q (%v)", info, "stub 1.0", err)
+	}
+
+	id, err := n.notify(0, "appointment-soon", "tea", "tea is over.", []string{"dismiss", "Dismiss"}, map[string]interface{}{"urgency": byte(2)}, -1)
+	if err != nil || id != 1 {
+		t.Fatalf("expected %d to eq 1 (%v)", id, err)
+	}
+	body := <-server.notified
+	if body[3] != "tea" || body[4] != "tea is over." || !reflect.DeepEqual(body[6], map[string]interface{}{"urgency": byte(2)}) {
+		t.Errorf("unexpected Notify %v", body)
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() {
+		server.emit("ActionInvoked", "us", id, "dismiss")
+	})
+	if key := n.actionInvoked(id, nil, time.Second); key != "dismiss" {
+		t.Errorf("expected %q to eq %q", key, "dismiss")
+	}
+
+	// Closing the notification ends waiting for the action.
+	time.AfterFunc(50*time.Millisecond, func() {
+		n.closeNotification(id)
+	})
+	if key := n.actionInvoked(id, nil, time.Second); key != "" {
+		t.Errorf("expected %q to be empty", key)
+	}
+
+	// The alarm reports the error from the server.
+	server.conn.close()
+	time.Sleep(50 * time.Millisecond)
+	if err := (dbusAlarm{bus: n}).Fire(notice{summary: "tea", body: "tea is over."}, nil); err == nil {
+		t.Errorf("expected error without the server")
+	}
+}
+
+func TestDBusAlarm_actions(t *testing.T) {
+	defer startBus(t)()
+	server := newStubServer(t, []string{"actions"})
+	defer server.conn.close()
+	n, err := dialNotifications()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.close()
+
+	actions := make(chan string, 1)
+	server.choices <- "extend"
+	if err := (dbusAlarm{bus: n}).Fire(notice{summary: "tea", body: "tea is over.", actions: actions}, nil); err != nil {
+		t.Fatal(err)
+	}
+	body := <-server.notified
+	if expected := []interface{}{"snooze", "Snooze", "extend", "+5 min", "dismiss", "Dismiss"}; !reflect.DeepEqual(body[5], expected) {
+		t.Errorf("expected %v to eq %v", body[5], expected)
+	}
+	if key := <-actions; key != "extend" {
+		t.Errorf("expected %q to eq %q", key, "extend")
+	}
+
+	// Without the capability, the notification has no actions.
+	n.caps = nil
+	if err := (dbusAlarm{bus: n}).Fire(notice{summary: "tea", body: "tea is over.", actions: actions}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-server.notified; len(body[5].([]interface{})) != 0 {
+		t.Errorf("expected %v to be empty", body[5])
+	}
+
+	// Markup is escaped only when the server interprets it.
+	n.caps = []string{"body-markup"}
+	if err := (dbusAlarm{bus: n}).Fire(notice{summary: "tea", body: "<tea> & cake"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-server.notified; body[4] != "&lt;tea&gt; &amp; cake" {
+		t.Errorf("expected %q to eq %q", body[4], "&lt;tea&gt; &amp; cake")
+	}
+
+	// The style is sent as the hints and the expiry.
+	style := notificationStyle{urgency: "critical", expire: neverExpire, category: "alarm"}
+	if err := (dbusAlarm{bus: n}).Fire(notice{summary: "tea", body: "tea is over.", style: style}, nil); err != nil {
+		t.Fatal(err)
+	}
+	body = <-server.notified
+	if expected := map[string]interface{}{"urgency": byte(2), "category": "alarm"}; !reflect.DeepEqual(body[6], expected) || body[7] != int32(0) {
+		t.Errorf("expected %v to eq %v with no expiry (%v)", body[6], expected, body[7])
+	}
+}
+
+func TestCLI_noServer(t *testing.T) {
+	defer startBus(t)()
+	if _, err := dialNotifications(); err == nil || !strings.Contains(err.Error(), "no notification server") {
+		t.Errorf("expected %v to contain %q", err, "no notification server")
+	}
+
+	// The default alarm falls back to terminal notification.
+	var out, errs bytes.Buffer
+	cli := &CLI{outStream: &out, errStream: &errs}
+	if status := cli.Run(strings.Split("time-to-go 1s tea", " ")); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errs.String())
+	}
+	if !strings.Contains(errs.String(), "Terminal notification is used instead.") || strings.Contains(errs.String(), "Alarm failed") {
+		t.Errorf("unexpected warning %q", errs.String())
+	}
+	if !strings.Contains(out.String(), "time-to-go: tea: tea is over.\n") {
+		t.Errorf("expected %q to contain the notification", out.String())
+	}
+}
+
+func TestCLI_snooze(t *testing.T) {
+	defer startBus(t)()
+	server := newStubServer(t, []string{"actions"})
+	defer server.conn.close()
+	server.choices <- "snooze"
+	server.choices <- "dismiss"
+
+	var out, errs bytes.Buffer
+	cli := &CLI{outStream: &out, errStream: &errs}
+	if status := cli.Run(strings.Split("time-to-go --output json --snooze 1s 1s tea", " ")); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errs.String())
+	}
+	var kinds []string
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var ev event
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Event != "tick" && ev.Event != "warning" {
+			kinds = append(kinds, ev.Event)
+		}
+	}
+	expected := []string{"started", "fired", "alarm", "snoozed", "started", "fired", "alarm"}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected %q to eq %q", kinds, expected)
+	}
+}
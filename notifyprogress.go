@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// notificationInterval is the minimum interval of updating the progress
+// notification. Notification servers animate every update, so updating
+// every second is too much.
+const notificationInterval = 5 * time.Second
+
+// notificationDisplay shows the progress of the countdown in a desktop
+// notification in addition to the display it wraps. The notification is
+// updated when the percentage changes, and is replaced by the
+// notification of the alarm.
+type notificationDisplay struct {
+	display
+	bus *notificationsClient
+	id  uint32
+	// last, percent and paused are of the last update.
+	last    time.Time
+	percent int
+	paused  bool
+}
+
+// show updates the notification to s.
+func (d *notificationDisplay) show(s *status) {
+	progress := ""
+	if s.total > 1 {
+		progress = fmt.Sprintf(" (step %d/%d)", s.no, s.total)
+	}
+	body := d.bus.bold(strings.TrimSpace(formatRemains(s.remains))) + d.bus.text(" remains"+s.pausedMark())
+	percent := newFormatData(s, "").Percent
+	hints := map[string]interface{}{
+		"value":   int32(percent),
+		"urgency": urgencies["low"],
+		// Progress is not worth keeping in the history.
+		"transient": true,
+	}
+	// The notification never expires while the countdown is running.
+	if id, err := d.bus.notify(d.id, "appointment-soon", notificationSummary(s.step, progress), body, nil, hints, 0); err == nil {
+		d.id = id
+	}
+	d.last, d.percent, d.paused = time.Now(), percent, s.paused
+}
+
+func (d *notificationDisplay) begin(s *status) {
+	d.display.begin(s)
+	d.id = 0
+	d.show(s)
+}
+
+func (d *notificationDisplay) update(s *status) {
+	d.display.update(s)
+	if s.paused != d.paused || newFormatData(s, "").Percent != d.percent && time.Since(d.last) >= notificationInterval {
+		d.show(s)
+	}
+}
+
+func (d *notificationDisplay) end(s *status) {
+	d.display.end(s)
+	if d.id != 0 {
+		d.bus.handOver(d.id)
+		d.id = 0
+	}
+}
+
+func (d *notificationDisplay) close() {
+	d.display.close()
+	// The notification is left when the countdown is cancelled or the
+	// alarm didn't replace it.
+	if d.id != 0 {
+		d.bus.closeNotification(d.id)
+		d.id = 0
+	}
+	if id := d.bus.takeOver(); id != 0 {
+		d.bus.closeNotification(id)
+	}
+}
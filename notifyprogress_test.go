@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCLI_progressNotification(t *testing.T) {
+	defer startBus(t)()
+	server := newStubServer(t, nil)
+	defer server.conn.close()
+
+	var out, errs bytes.Buffer
+	cli := &CLI{outStream: &out, errStream: &errs}
+	if status := cli.Run(strings.Split("time-to-go --alarm dbus --progress-notification 2s tea", " ")); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errs.String())
+	}
+
+	progress := <-server.notified
+	if progress[1] != uint32(0) || progress[3] != "time-to-go: tea" || progress[4] != "02s remains" {
+		t.Errorf("unexpected Notify %v", progress)
+	}
+	hints := progress[6].(map[string]interface{})
+	if hints["value"] != int32(0) || hints["transient"] != true {
+		t.Errorf("unexpected hints %v", hints)
+	}
+	// The alarm replaces the progress notification.
+	alarm := <-server.notified
+	if alarm[1] != uint32(1) || alarm[3] != "time-to-go: tea" {
+		t.Errorf("unexpected Notify %v", alarm)
+	}
+}
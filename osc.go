@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// notifiers are the ways to show the notification of the alarm.
+// dbus and libnotify show desktop notification on the machine running
+// time-to-go. The others write escape sequences so that the terminal
+// shows the notification on the machine the user is sitting at.
+var notifiers = []string{"dbus", "libnotify", "terminal", "osc9", "osc777", "osc99"}
+
+// detectOSC guesses the terminal notification sequence supported by
+// the terminal from environment variables.
+func detectOSC() string {
+	term := os.Getenv("TERM")
+	switch {
+	case term == "xterm-kitty" || os.Getenv("KITTY_WINDOW_ID") != "":
+		return "osc99"
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "rxvt-unicode"):
+		return "osc777"
+	default:
+		// iTerm2, WezTerm, Windows Terminal and many others.
+		return "osc9"
+	}
+}
+
+// oscNotification returns the escape sequence of terminal notification
+// of kind with summary and body.
+func oscNotification(kind, summary, body string) string {
+	summary, body = oscText(summary), oscText(body)
+	switch kind {
+	case "osc777":
+		// urxvt and foot. ";" separates the fields.
+		return "\x1b]777;notify;" + strings.Replace(summary, ";", ",", -1) + ";" + body + "\x1b\\"
+	case "osc99":
+		// kitty. The title and the body are sent as chunks of a
+		// notification identified by i.
+		return "\x1b]99;i=time-to-go:d=0:p=title;" + summary + "\x1b\\" +
+			"\x1b]99;i=time-to-go:d=1:p=body;" + body + "\x1b\\"
+	default:
+		// iTerm2 and WezTerm show only a message.
+		return "\x1b]9;" + summary + ": " + body + "\x1b\\"
+	}
+}
+
+// oscText removes control characters which terminate the sequence.
+func oscText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+}
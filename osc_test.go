@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestOSCNotification(t *testing.T) {
+	cases := []struct {
+		kind, expected string
+	}{
+		{"osc9", "\x1b]9;time-to-go: tea is over.\x1b\\"},
+		{"osc777", "\x1b]777;notify;time-to-go, tea;tea is over.\x1b\\"},
+		{"osc99", "\x1b]99;i=time-to-go:d=0:p=title;time-to-go; tea\x1b\\\x1b]99;i=time-to-go:d=1:p=body;tea is over.\x1b\\"},
+	}
+	for _, c := range cases {
+		summary := "time-to-go; tea"
+		if c.kind == "osc9" {
+			summary = "time-to-go"
+		}
+		if s := oscNotification(c.kind, summary, "tea\x07is over."); s != c.expected {
+			t.Errorf("expected %q to eq %q", s, c.expected)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// pauseState shares the pause of a step with its sub-timers. A nil
+// pauseState is never paused.
+type pauseState struct {
+	mu     sync.Mutex
+	paused bool
+	// changed is closed and replaced when paused changes.
+	changed chan struct{}
+}
+
+func newPauseState() *pauseState {
+	return &pauseState{changed: make(chan struct{})}
+}
+
+// set pauses or resumes the waiters of sleep.
+func (p *pauseState) set(paused bool) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused == paused {
+		return
+	}
+	p.paused = paused
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// get returns whether it is paused and the channel closed at the next
+// change.
+func (p *pauseState) get() (bool, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused, p.changed
+}
+
+// sleep waits until d passes while it is not paused. It returns false
+// when done is closed first.
+func (p *pauseState) sleep(d time.Duration, done <-chan struct{}) bool {
+	for {
+		paused, changed := p.get()
+		if paused {
+			select {
+			case <-changed:
+				continue
+			case <-done:
+				return false
+			}
+		}
+		start := time.Now()
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+			return true
+		case <-changed:
+			t.Stop()
+			d -= time.Since(start)
+		case <-done:
+			t.Stop()
+			return false
+		}
+	}
+}
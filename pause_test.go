@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauseState_sleep(t *testing.T) {
+	p := newPauseState()
+	time.AfterFunc(20*time.Millisecond, func() {
+		p.set(true)
+	})
+	time.AfterFunc(120*time.Millisecond, func() {
+		p.set(false)
+	})
+	start := time.Now()
+	if !p.sleep(50*time.Millisecond, nil) {
+		t.Fatal("expected sleep to complete")
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("expected %v to include the pause", d)
+	}
+
+	// It stops at done while paused.
+	p.set(true)
+	done := make(chan struct{})
+	close(done)
+	if p.sleep(time.Millisecond, done) {
+		t.Errorf("expected sleep to be stopped")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Blocks to draw the progress bar. Unicode blocks have eighths to draw
+// the fraction of a cell.
+var (
+	unicodeBlocks = []string{" ", "▏", "▎", "▍", "▌", "▋", "▊", "▉", "█"}
+	asciiBlocks   = []string{"-", "#"}
+)
+
+// progressDisplay overwrites a single line with a progress bar showing
+// elapsed and total time, percentage and the wall-clock ETA.
+type progressDisplay struct {
+	liveLine
+	f     *os.File
+	ascii bool
+}
+
+// newProgressDisplay returns progressDisplay which uses ASCII
+// characters unless the locale is UTF-8.
+func newProgressDisplay(l liveLine) *progressDisplay {
+	d := &progressDisplay{liveLine: l, ascii: !isUTF8Locale()}
+	d.f, _ = l.w.(*os.File)
+	return d
+}
+
+func (d *progressDisplay) begin(s *status) {
+	fmt.Fprintf(d.w, "Sleeping %v%s\n", formatDuration(s.step.duration), s.step.suffix())
+	d.update(s)
+}
+
+func (d *progressDisplay) update(s *status) {
+	d.liveLine.update(s, d.line(s, d.cols()))
+}
+
+func (d *progressDisplay) end(s *status) {
+	d.liveLine.end(d.line(s, d.cols()))
+}
+
+// cols returns the width of the terminal, or 80 if it is unknown.
+func (d *progressDisplay) cols() int {
+	if d.f != nil {
+		if c, _, ok := terminalSize(d.f); ok {
+			return c
+		}
+	}
+	return 80
+}
+
+func (d *progressDisplay) close() {}
+
+// line renders the progress of s to fit in cols columns. Less
+// important fields are dropped on narrow terminals to keep the bar.
+func (d *progressDisplay) line(s *status, cols int) string {
+	total := int(s.step.duration.Seconds())
+	elapsed := total - s.remains
+	ratio := 1.0
+	if total > 0 {
+		ratio = float64(elapsed) / float64(total)
+	}
+	eta := s.start.Add(s.step.duration)
+
+	fields := []string{
+		fmt.Sprintf("%3d%%", int(ratio*100)),
+		fmt.Sprintf("%s/%s", clock(elapsed), clock(total)),
+		"ETA " + eta.Format("15:04:05"),
+	}
+	if caption := strings.TrimSpace(s.step.label + s.progress() + s.pausedMark()); caption != "" {
+		fields = append(fields, caption)
+	}
+	// Leave the last column to avoid wrapping.
+	const minBar = 10
+	n := len(fields)
+	for ; n > 1; n-- {
+		if cols-1-textWidth(fields[:n])-2 >= minBar {
+			break
+		}
+	}
+	width := cols - 1 - textWidth(fields[:n]) - 2
+	if width < 1 {
+		return fields[0]
+	}
+	return "[" + d.bar(ratio, width) + "] " + strings.Join(fields[:n], " ")
+}
+
+// bar draws ratio in width cells.
+func (d *progressDisplay) bar(ratio float64, width int) string {
+	blocks := unicodeBlocks
+	if d.ascii {
+		blocks = asciiBlocks
+	}
+	steps := len(blocks) - 1
+	filled := int(ratio * float64(width*steps))
+	var b strings.Builder
+	for i := 0; i < width; i++ {
+		switch n := filled - i*steps; {
+		case n >= steps:
+			b.WriteString(blocks[steps])
+		case n > 0:
+			b.WriteString(blocks[n])
+		default:
+			b.WriteString(blocks[0])
+		}
+	}
+	return b.String()
+}
+
+// textWidth returns the width of fields joined by space, following a
+// space after the bar.
+func textWidth(fields []string) int {
+	w := 0
+	for _, f := range fields {
+		w += 1 + len([]rune(f))
+	}
+	return w
+}
+
+// isUTF8Locale reports whether the locale set by environment variables
+// uses UTF-8.
+func isUTF8Locale() bool {
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(key); v != "" {
+			v = strings.ToUpper(v)
+			return strings.Contains(v, "UTF-8") || strings.Contains(v, "UTF8")
+		}
+	}
+	return false
+}
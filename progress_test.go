@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressDisplay_line(t *testing.T) {
+	d := &progressDisplay{ascii: true}
+	s := &status{
+		step:    step{duration: 100 * time.Second, label: "tea"},
+		remains: 25,
+		start:   time.Date(2018, 1, 16, 12, 0, 0, 0, time.Local),
+	}
+
+	line := d.line(s, 80)
+	if len(line) != 79 {
+		t.Errorf("expected width %d to eq %d: %q", len(line), 79, line)
+	}
+	for _, expected := range []string{" 75%", "01:15/01:40", "ETA 12:01:40", "tea"} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("expected %q to contain %q", line, expected)
+		}
+	}
+
+	// ETA and the label are dropped on narrow terminals.
+	line = d.line(s, 30)
+	if len(line) > 29 || strings.Contains(line, "ETA") || !strings.Contains(line, "75%") {
+		t.Errorf("unexpected line on narrow terminal: %q", line)
+	}
+}
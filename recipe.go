@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// recipe is a multi-step timed procedure described in YAML or TOML file.
+//
+// YAML example:
+//
+//	name: Black tea
+//	steps:
+//	  - duration: 3min
+//	    label: steep
+//	    instructions: Pour boiling water over the leaves.
+//	    confirm: true
+//	    parallel:
+//	      - duration: 1min
+//	        label: stir
+//	  - duration: 2min
+//	    label: cool
+//
+// TOML example:
+//
+//	name = "Black tea"
+//
+//	[[steps]]
+//	duration = "3min"
+//	label = "steep"
+//	confirm = true
+//
+//	[[steps.parallel]]
+//	duration = "1min"
+//	label = "stir"
+type recipe struct {
+	name  string
+	steps []step
+}
+
+// loadRecipe reads the recipe file at path. The format is chosen by its
+// extension.
+func loadRecipe(path string) (*recipe, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		doc, err = parseYAML(data)
+	case ".toml":
+		doc, err = parseTOML(data)
+	default:
+		return nil, fmt.Errorf("%s: unknown recipe format (must be .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	r, err := decodeRecipe(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// parseYAML converts YAML document to map[string]interface{}.
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return normalizeTable(doc), nil
+}
+
+// parseTOML converts TOML document to map[string]interface{}.
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, err
+	}
+	return normalizeTable(doc), nil
+}
+
+// normalizeTable converts the tables, the lists and the integers decoded
+// from YAML and TOML to map[string]interface{}, []interface{} and int64
+// so that they are decoded alike.
+func normalizeTable(m map[string]interface{}) map[string]interface{} {
+	t := map[string]interface{}{}
+	for k, v := range m {
+		t[k] = normalizeValue(v)
+	}
+	return t
+}
+
+// normalizeValue converts v as normalizeTable does.
+func normalizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return normalizeTable(v)
+	case map[interface{}]interface{}:
+		t := map[string]interface{}{}
+		for k, e := range v {
+			t[fmt.Sprint(k)] = normalizeValue(e)
+		}
+		return t
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, e := range v {
+			list[i] = normalizeTable(e)
+		}
+		return list
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, e := range v {
+			list[i] = normalizeValue(e)
+		}
+		return list
+	case int:
+		return int64(v)
+	}
+	return v
+}
+
+// decodeRecipe converts the parsed document to recipe and validates it.
+func decodeRecipe(doc map[string]interface{}) (*recipe, error) {
+	r := &recipe{}
+	for k, v := range doc {
+		switch k {
+		case "name":
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("name must be a string")
+			}
+			r.name = s
+		case "steps":
+		default:
+			return nil, fmt.Errorf("unknown key %q", k)
+		}
+	}
+	steps, err := decodeSteps(doc["steps"], "steps", true)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no steps")
+	}
+	r.steps = steps
+	return r, nil
+}
+
+// decodeSteps converts a list of step tables. Sub-timers given by
+// "parallel" cannot be nested, so it is allowed only when top is true.
+func decodeSteps(v interface{}, path string, top bool) ([]step, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list", path)
+	}
+	var steps []step
+	for i, item := range list {
+		where := fmt.Sprintf("%s[%d]", path, i)
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a table", where)
+		}
+		var st step
+		for k, v := range m {
+			var err error
+			switch k {
+			case "duration":
+				st.duration, err = decodeDuration(v)
+			case "label":
+				st.label, err = decodeString(v)
+			case "instructions":
+				// They are printed as lines, so the line break which ends
+				// the block scalar of YAML is dropped.
+				st.instructions, err = decodeString(v)
+				st.instructions = strings.TrimRight(st.instructions, "\n")
+			case "confirm":
+				if !top {
+					return nil, fmt.Errorf("%s: confirm is not allowed for parallel timer", where)
+				}
+				st.confirm, err = decodeBool(v)
+			case "parallel":
+				if !top {
+					return nil, fmt.Errorf("%s: parallel timer cannot be nested", where)
+				}
+				st.parallel, err = decodeSteps(v, where+".parallel", false)
+			case "alarm":
+				st.alarms, err = decodeAlarms(v)
+			case "notification":
+				st.notification, err = decodeNotificationStyle(v)
+			default:
+				err = fmt.Errorf("unknown key %q", k)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", where, err)
+			}
+		}
+		if st.duration <= 0 {
+			return nil, fmt.Errorf("%s: duration is required", where)
+		}
+		steps = append(steps, st)
+	}
+	return steps, nil
+}
+
+// decodeDuration accepts TIME string or a number in seconds.
+func decodeDuration(v interface{}) (time.Duration, error) {
+	switch d := v.(type) {
+	case int64:
+		return time.Duration(d) * time.Second, nil
+	case float64:
+		return time.Duration(d * float64(time.Second)), nil
+	case string:
+		if n, err := strconv.Atoi(d); err == nil {
+			return time.Duration(n) * time.Second, nil
+		}
+		if t, err := parseInterval(d); err == nil && t > 0 {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid duration %v", v)
+}
+
+// decodeExpire accepts the values of --expire or seconds, where zero is
+// the default of the server. YAML and TOML give the same expiry for 0.
+func decodeExpire(v interface{}) (time.Duration, error) {
+	if str, ok := v.(string); ok {
+		if _, err := strconv.Atoi(str); err != nil {
+			return parseExpire(str)
+		}
+	}
+	d, err := decodeDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Invalid expiry: %v", v)
+	}
+	return d, nil
+}
+
+// decodeAlarms accepts comma separated names of alarms or a list of them.
+func decodeAlarms(v interface{}) ([]string, error) {
+	if s, ok := v.(string); ok {
+		return parseAlarms(s)
+	}
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("alarm must be a string or a list of strings")
+	}
+	var names []string
+	for _, item := range list {
+		s, err := decodeString(item)
+		if err != nil {
+			return nil, err
+		}
+		n, err := parseAlarms(s)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, n...)
+	}
+	return names, nil
+}
+
+// decodeNotificationStyle converts a table with the keys named after the
+// flags of desktop notification.
+func decodeNotificationStyle(v interface{}) (notificationStyle, error) {
+	var s notificationStyle
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return s, fmt.Errorf("notification must be a table")
+	}
+	for k, v := range m {
+		var err error
+		switch k {
+		case "urgency":
+			s.urgency, err = decodeString(v)
+		case "expire":
+			s.expire, err = decodeExpire(v)
+		case "category":
+			s.category, err = decodeString(v)
+		case "icon":
+			s.icon, err = decodeString(v)
+		case "sound-file":
+			s.soundFile, err = decodeString(v)
+		case "sound-name":
+			s.soundName, err = decodeString(v)
+		case "resident":
+			s.resident, err = decodeBool(v)
+		case "transient":
+			s.transient, err = decodeBool(v)
+		default:
+			err = fmt.Errorf("unknown key %q", k)
+		}
+		if err != nil {
+			return s, fmt.Errorf("notification: %v", err)
+		}
+	}
+	if err := s.validate(); err != nil {
+		return s, fmt.Errorf("notification: %v", err)
+	}
+	s.icon = iconPath(s.icon)
+	return s, nil
+}
+
+func decodeString(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%v must be a string", v)
+	}
+	return s, nil
+}
+
+func decodeBool(v interface{}) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case string:
+		switch strings.ToLower(b) {
+		case "true", "yes", "on":
+			return true, nil
+		case "false", "no", "off":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("%v must be a boolean", v)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const yamlRecipe = `# Black tea
+name: Black tea
+steps:
+  - duration: 3min
+    label: steep
+    instructions: |
+      Pour boiling water.
+      Cover the pot.
+    confirm: yes
+    parallel:
+      - duration: 60
+        label: 'stir'
+  - duration: "2:00" # cool down
+    label: cool
+    alarm: [bell, 'print']
+    notification:
+      expire: 0
+`
+
+const tomlRecipe = `# Black tea
+name = "Black tea"
+
+[[steps]]
+duration = "3min"
+label = "steep"
+instructions = "Pour boiling water.\nCover the pot."
+confirm = true
+
+[[steps.parallel]]
+duration = 60
+label = 'stir'
+
+[[steps]]
+duration = "2:00" # cool down
+label = "cool"
+alarm = ["bell", 'print']
+
+[steps.notification]
+expire = 0
+`
+
+func TestDecodeRecipe(t *testing.T) {
+	for format, parse := range map[string]func([]byte) (map[string]interface{}, error){
+		"yaml": parseYAML,
+		"toml": parseTOML,
+	} {
+		src := yamlRecipe
+		if format == "toml" {
+			src = tomlRecipe
+		}
+		doc, err := parse([]byte(src))
+		if err != nil {
+			t.Fatalf("%s: parse returned error: %v", format, err)
+		}
+		r, err := decodeRecipe(doc)
+		if err != nil {
+			t.Fatalf("%s: decodeRecipe returned error: %v", format, err)
+		}
+		if r.name != "Black tea" || len(r.steps) != 2 {
+			t.Fatalf("%s: unexpected recipe %+v", format, r)
+		}
+		st := r.steps[0]
+		if st.duration != 3*time.Minute || st.label != "steep" || !st.confirm {
+			t.Errorf("%s: unexpected step %+v", format, st)
+		}
+		if st.instructions != "Pour boiling water.\nCover the pot." {
+			t.Errorf("%s: expected %q to eq %q", format, st.instructions, "Pour boiling water.\nCover the pot.")
+		}
+		if len(st.parallel) != 1 || st.parallel[0].duration != time.Minute || st.parallel[0].label != "stir" {
+			t.Errorf("%s: unexpected parallel timers %+v", format, st.parallel)
+		}
+		if r.steps[1].duration != 2*time.Minute || r.steps[1].label != "cool" {
+			t.Errorf("%s: unexpected step %+v", format, r.steps[1])
+		}
+		if expected := []string{"bell", "print"}; !reflect.DeepEqual(r.steps[1].alarms, expected) {
+			t.Errorf("%s: expected %q to eq %q", format, r.steps[1].alarms, expected)
+		}
+		if e := r.steps[1].notification.expire; e != 0 {
+			t.Errorf("%s: expected %v to eq 0", format, e)
+		}
+	}
+}
+
+func TestParseRecipe(t *testing.T) {
+	// Flow mappings, inline tables, floats and multi-line strings.
+	cases := map[string]string{
+		"yaml": "steps:\n  - {duration: 1min, label: steep}\n  - duration: 1.5\n    instructions: |\n      line1\n\n      # not a comment\n      line3\n",
+		"toml": "steps = [{duration = \"1min\", label = \"steep\"}, {duration = 1.5, instructions = \"\"\"\nline1\n\n# not a comment\nline3\n\"\"\"}]\n",
+	}
+	for format, src := range cases {
+		parse := parseYAML
+		if format == "toml" {
+			parse = parseTOML
+		}
+		doc, err := parse([]byte(src))
+		if err != nil {
+			t.Fatalf("%s: parse returned error: %v", format, err)
+		}
+		r, err := decodeRecipe(doc)
+		if err != nil {
+			t.Fatalf("%s: decodeRecipe returned error: %v", format, err)
+		}
+		if len(r.steps) != 2 || r.steps[0].duration != time.Minute || r.steps[0].label != "steep" {
+			t.Fatalf("%s: unexpected recipe %+v", format, r)
+		}
+		if d := r.steps[1].duration; d != 1500*time.Millisecond {
+			t.Errorf("%s: expected %v to eq 1.5s", format, d)
+		}
+		if expected := "line1\n\n# not a comment\nline3"; r.steps[1].instructions != expected {
+			t.Errorf("%s: expected %q to eq %q", format, r.steps[1].instructions, expected)
+		}
+	}
+
+	// The table doesn't redefine the array of tables.
+	if _, err := parseTOML([]byte("[[steps]]\nduration = 1\n[steps]\n")); err == nil {
+		t.Errorf("expected error for the table redefining the array")
+	}
+}
+
+func TestReadmeRecipes(t *testing.T) {
+	data, err := ioutil.ReadFile("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, format := range []string{"yaml", "toml"} {
+		parse := parseYAML
+		if format == "toml" {
+			parse = parseTOML
+		}
+		blocks := strings.Split(string(data), "```"+format+"\n")
+		for _, b := range blocks[1:] {
+			src := b[:strings.Index(b, "```")]
+			doc, err := parse([]byte(src))
+			if err == nil {
+				_, err = decodeRecipe(doc)
+			}
+			if err != nil {
+				t.Errorf("%s: expected the example to be valid: %v", format, err)
+			}
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("expected %d to eq 2 examples", found)
+	}
+}
+
+func TestDecodeRecipe_invalid(t *testing.T) {
+	cases := map[string]string{
+		"steps:\n  - label: no duration\n":                   "duration is required",
+		"steps:\n  - duration: 1min\n    colour: red\n":      "unknown key",
+		"steps:\n  - duration: soon\n":                       "invalid duration",
+		"name: empty\n":                                      "no steps",
+		"steps:\n  - duration: 1min\n    confirm: perhaps\n": "must be a boolean",
+		"steps:\n  - duration: 1min\n    alarm: siren\n":     "Unknown alarm",
+		"steps:\n  - duration: 1min\n    parallel:\n      - duration: 1min\n        confirm: true\n": "not allowed",
+		"steps:\n  - duration: 1min\n    notification:\n      urgency: loud\n":                       "Unknown urgency",
+		"steps:\n  - duration: 1min\n    notification:\n      expire: -1\n":                          "Invalid expiry",
+		"steps:\n  - duration: 1min\n    alarm: [bell, siren]\n":                                     "Unknown alarm",
+	}
+	for src, expected := range cases {
+		doc, err := parseYAML([]byte(src))
+		if err == nil {
+			_, err = decodeRecipe(doc)
+		}
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %v to contain %q", err, expected)
+		}
+	}
+}
+
+func TestCLI_runParallel(t *testing.T) {
+	f, err := ioutil.TempFile("", "recipe*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("name: Tea\nsteps:\n  - duration: 1\n    label: steep\n    parallel:\n      - duration: 2\n        label: stir\n")
+	f.Close()
+
+	var out, errs bytes.Buffer
+	cli := &CLI{outStream: &out, errStream: &errs}
+	if status := cli.Run([]string{"time-to-go", "--output", "json", "--alarm", "print", "run", f.Name()}); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errs.String())
+	}
+	var fired []string
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var ev event
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Event == "fired" {
+			fired = append(fired, ev.Label)
+		}
+	}
+	// The step fires without waiting for the longer sub-timer.
+	expected := []string{"steep", "stir"}
+	if !reflect.DeepEqual(fired, expected) {
+		t.Errorf("expected %q to eq %q", fired, expected)
+	}
+	// The name of the recipe is written with the human readable output.
+	if !strings.HasPrefix(errs.String(), "Tea\n") {
+		t.Errorf("expected %q to start with the name", errs.String())
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// sampleRate is the rate of the embedded sounds.
+const sampleRate = 44100
+
+// note is a sine tone of freq Hz lasting d. Zero freq is a rest.
+type note struct {
+	freq float64
+	d    time.Duration
+}
+
+// sounds are the embedded sounds selected by --sound, which are
+// synthesized when they are loaded.
+var sounds = map[string][]note{
+	"beep": {{880, 300 * time.Millisecond}},
+	// Descending E6, C6 and G5 like a doorbell.
+	"chime": {{1319, 250 * time.Millisecond}, {1047, 250 * time.Millisecond}, {784, 600 * time.Millisecond}},
+	// Four short beeps of an alarm clock.
+	"alarm": {
+		{988, 100 * time.Millisecond}, {0, 50 * time.Millisecond},
+		{988, 100 * time.Millisecond}, {0, 50 * time.Millisecond},
+		{988, 100 * time.Millisecond}, {0, 50 * time.Millisecond},
+		{988, 100 * time.Millisecond}, {0, 450 * time.Millisecond},
+	},
+}
+
+// players are tried in order when --sound-player is not given. They read
+// WAV from stdin.
+var players = [][]string{
+	{"paplay"},
+	{"pw-play", "-"},
+	{"aplay", "-q", "-"},
+	{"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet", "-"},
+}
+
+// pcm is signed 16-bit samples interleaved by channels.
+type pcm struct {
+	samples  []int16
+	rate     int
+	channels int
+}
+
+// duration returns the length of p.
+func (p *pcm) duration() time.Duration {
+	return time.Duration(len(p.samples)/p.channels) * time.Second / time.Duration(p.rate)
+}
+
+// synthesize renders notes at sampleRate. Each note fades in and out to
+// avoid clicks.
+func synthesize(notes []note) *pcm {
+	fade := sampleRate / 200
+	p := &pcm{rate: sampleRate, channels: 1}
+	for _, n := range notes {
+		count := int(n.d.Seconds() * sampleRate)
+		for i := 0; i < count; i++ {
+			if n.freq == 0 {
+				p.samples = append(p.samples, 0)
+				continue
+			}
+			gain := 0.5
+			if i < fade {
+				gain *= float64(i) / float64(fade)
+			} else if count-i < fade {
+				gain *= float64(count-i) / float64(fade)
+			}
+			v := gain * math.Sin(2*math.Pi*n.freq*float64(i)/sampleRate)
+			p.samples = append(p.samples, int16(v*math.MaxInt16))
+		}
+	}
+	return p
+}
+
+// ramped returns p played after elapsed with the gain rising linearly
+// from a tenth to full over ramp.
+func (p *pcm) ramped(elapsed, ramp time.Duration) *pcm {
+	if ramp <= 0 || elapsed >= ramp {
+		return p
+	}
+	r := &pcm{samples: make([]int16, len(p.samples)), rate: p.rate, channels: p.channels}
+	start := elapsed.Seconds() * float64(p.rate)
+	total := ramp.Seconds() * float64(p.rate)
+	for i, v := range p.samples {
+		gain := 0.1 + 0.9*(start+float64(i/p.channels))/total
+		if gain > 1 {
+			gain = 1
+		}
+		r.samples[i] = int16(float64(v) * gain)
+	}
+	return r
+}
+
+// raw returns the samples in little endian.
+func (p *pcm) raw() []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, p.samples)
+	return b.Bytes()
+}
+
+// wav returns p in WAV format.
+func (p *pcm) wav() []byte {
+	data := p.raw()
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+len(data)))
+	b.WriteString("WAVEfmt ")
+	for _, v := range []interface{}{
+		uint32(16), uint16(1), uint16(p.channels), uint32(p.rate),
+		uint32(p.rate * p.channels * 2), uint16(p.channels * 2), uint16(16),
+	} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+// errNotPCM is returned for WAV files which are not 16-bit PCM and for
+// other formats.
+var errNotPCM = errors.New("not a 16-bit PCM WAV file")
+
+// decodeWAV decodes 16-bit PCM WAV.
+func decodeWAV(data []byte) (*pcm, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errNotPCM
+	}
+	var p *pcm
+	for rest := data[12:]; len(rest) >= 8; {
+		id, size := string(rest[:4]), int(binary.LittleEndian.Uint32(rest[4:8]))
+		rest = rest[8:]
+		if size > len(rest) {
+			size = len(rest)
+		}
+		chunk := rest[:size]
+		switch {
+		case id == "fmt " && size >= 16:
+			format, channels := binary.LittleEndian.Uint16(chunk), binary.LittleEndian.Uint16(chunk[2:])
+			rate, bits := binary.LittleEndian.Uint32(chunk[4:]), binary.LittleEndian.Uint16(chunk[14:])
+			if format != 1 || bits != 16 || channels == 0 || rate == 0 {
+				return nil, errNotPCM
+			}
+			p = &pcm{rate: int(rate), channels: int(channels)}
+		case id == "data" && p != nil:
+			p.samples = make([]int16, size/2)
+			binary.Read(bytes.NewReader(chunk[:size/2*2]), binary.LittleEndian, p.samples)
+			return p, nil
+		}
+		// Chunks are padded to even size, which a truncated file lacks.
+		if size+size%2 > len(rest) {
+			break
+		}
+		rest = rest[size+size%2:]
+	}
+	return nil, errNotPCM
+}
+
+// soundConfig is the sound played by the sound alarm.
+type soundConfig struct {
+	// pcm is the decoded sound. It is nil for the file which only the
+	// player can decode, whose content is data.
+	pcm  *pcm
+	data []byte
+	// player is the shell command which reads WAV from stdin. Empty is
+	// the first found in players.
+	player string
+	// sink is the path where raw PCM is written instead of the player.
+	sink string
+	// untilAck repeats the sound of the last alarm until it is
+	// acknowledged.
+	untilAck bool
+	// ramp is the time the volume rises to full.
+	ramp time.Duration
+}
+
+// loadSound returns the embedded sound of name, or reads the file.
+func loadSound(name string) (soundConfig, error) {
+	if notes, ok := sounds[name]; ok {
+		return soundConfig{pcm: synthesize(notes)}, nil
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return soundConfig{}, err
+	}
+	p, err := decodeWAV(data)
+	if err != nil {
+		// Let the player decode other formats.
+		return soundConfig{data: data}, nil
+	}
+	return soundConfig{pcm: p}, nil
+}
+
+// validate checks the sound can be played as configured.
+func (c soundConfig) validate() error {
+	if c.pcm == nil && c.sink != "" {
+		return fmt.Errorf("--sound-sink needs the sound in WAV: %v", errNotPCM)
+	}
+	if c.pcm == nil && c.ramp > 0 {
+		return fmt.Errorf("--sound-ramp needs the sound in WAV: %v", errNotPCM)
+	}
+	return nil
+}
+
+// soundGap is the gap between the repeats of the sound. It doubles up to
+// soundMaxGap while the player ends too soon not to spin.
+const (
+	soundGap    = 200 * time.Millisecond
+	soundMaxGap = 5 * time.Second
+)
+
+// soundAlarm plays the sound with the player or writes it to the sink.
+type soundAlarm struct {
+	sound soundConfig
+	// w receives the output of the player.
+	w io.Writer
+}
+
+func (a soundAlarm) Fire(n notice, ack <-chan struct{}) error {
+	var elapsed time.Duration
+	gap := soundGap
+	for {
+		start := time.Now()
+		// d is the length of the sound, which is unknown to the player.
+		var d time.Duration
+		var err error
+		if a.sound.pcm == nil {
+			err = a.play(a.sound.data, ack)
+		} else {
+			p := a.sound.pcm.ramped(elapsed, a.sound.ramp)
+			d = p.duration()
+			if a.sound.sink != "" {
+				err = a.write(p, ack)
+			} else {
+				err = a.play(p.wav(), ack)
+			}
+			elapsed += d
+		}
+		if err != nil {
+			return err
+		}
+		if !a.sound.untilAck || !n.last {
+			return nil
+		}
+		select {
+		case <-ack:
+			return nil
+		default:
+		}
+		// The player which ends too soon didn't play the sound.
+		if ran := time.Since(start); ran < d/2 || d == 0 && ran < soundGap {
+			if gap *= 2; gap > soundMaxGap {
+				gap = soundMaxGap
+			}
+		} else {
+			gap = soundGap
+		}
+		t := time.NewTimer(gap)
+		select {
+		case <-ack:
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// play pipes data to the player, which is killed when ack is closed.
+func (a soundAlarm) play(data []byte, ack <-chan struct{}) error {
+	var cmd *exec.Cmd
+	if a.sound.player != "" {
+		cmd = exec.Command("sh", "-c", a.sound.player)
+	} else {
+		for _, p := range players {
+			if path, err := exec.LookPath(p[0]); err == nil {
+				cmd = exec.Command(path, p[1:]...)
+				break
+			}
+		}
+		if cmd == nil {
+			return errors.New("no sound player is found")
+		}
+	}
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout, cmd.Stderr = a.w, a.w
+	return runUntilAck(cmd, ack)
+}
+
+// write writes p to the sink as raw PCM. It waits until p is played
+// since the sink may not block, such as a file.
+func (a soundAlarm) write(p *pcm, ack <-chan struct{}) error {
+	f, err := os.OpenFile(a.sound.sink, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	_, err = f.Write(p.raw())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	t := time.NewTimer(p.duration() - time.Since(start))
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ack:
+	}
+	return nil
+}
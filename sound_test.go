@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeWAV(t *testing.T) {
+	p := synthesize(sounds["chime"])
+	if d := p.duration(); d != 1100*time.Millisecond {
+		t.Errorf("expected %v to eq %v", d, 1100*time.Millisecond)
+	}
+	r, err := decodeWAV(p.wav())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, p) {
+		t.Errorf("expected decoded WAV to eq the original")
+	}
+	// The file truncated in the chunk of odd size has no padding.
+	for _, data := range [][]byte{[]byte("OggS"), []byte("RIFF\x00\x00\x00\x00WAVELIST\x05\x00\x00\x00abc")} {
+		if _, err := decodeWAV(data); err != errNotPCM {
+			t.Errorf("expected %v to eq %v", err, errNotPCM)
+		}
+	}
+}
+
+// peak returns the largest sample of raw PCM.
+func peak(raw []byte) int16 {
+	var max int16
+	for i := 0; i+1 < len(raw); i += 2 {
+		if v := int16(raw[i]) | int16(raw[i+1])<<8; v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+func TestSoundAlarm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "time-to-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The sound is repeated until ack with the volume rising.
+	c, err := loadSound("beep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.sink, c.untilAck, c.ramp = filepath.Join(dir, "sink"), true, time.Second
+	ack := make(chan struct{})
+	time.AfterFunc(650*time.Millisecond, func() {
+		close(ack)
+	})
+	if err := (soundAlarm{sound: c}).Fire(notice{last: true}, ack); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := ioutil.ReadFile(c.sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := len(c.pcm.raw())
+	if len(raw) != 2*size {
+		t.Fatalf("expected %d to eq %d", len(raw), 2*size)
+	}
+	if first, second := peak(raw[:size]), peak(raw[size:]); first >= second || second >= peak(c.pcm.raw()) {
+		t.Errorf("expected %d < %d < %d", first, second, peak(c.pcm.raw()))
+	}
+
+	// The player reads WAV from stdin.
+	out := filepath.Join(dir, "out.wav")
+	c.sink, c.player = "", "cat > "+out
+	if err := (soundAlarm{sound: c}).Fire(notice{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(out); !bytes.HasPrefix(data, []byte("RIFF")) {
+		t.Errorf("expected %q to be WAV", data[:4])
+	}
+
+	// The player which ends at once is repeated with growing gaps.
+	runs := filepath.Join(dir, "runs")
+	c.player, c.untilAck = "echo >> "+runs, true
+	ack = make(chan struct{})
+	time.AfterFunc(time.Second, func() {
+		close(ack)
+	})
+	if err := (soundAlarm{sound: c}).Fire(notice{last: true}, ack); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(runs); len(data) != 2 {
+		t.Errorf("expected %d to eq 2", len(data))
+	}
+
+	// Other formats are passed to the player as they are.
+	ogg := filepath.Join(dir, "bell.ogg")
+	ioutil.WriteFile(ogg, []byte("OggS"), 0644)
+	if c, err = loadSound(ogg); err != nil || c.pcm != nil {
+		t.Fatalf("expected %s to be left to the player (%v)", ogg, err)
+	}
+	c.sink = "/dev/null"
+	if err := c.validate(); err == nil {
+		t.Errorf("expected error for raw PCM of %s", ogg)
+	}
+}
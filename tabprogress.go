@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// States of the progress indicator set by OSC 9;4 of ConEmu, which is
+// supported by Windows Terminal and WezTerm too.
+const (
+	tabProgressRemove = 0
+	tabProgressNormal = 1
+	tabProgressError  = 2
+	tabProgressPaused = 4
+)
+
+// tabProgressDisplay shows the progress of the countdown on the tab or
+// the taskbar in addition to the display it wraps.
+type tabProgressDisplay struct {
+	display
+	w      io.Writer
+	closed bool
+}
+
+// setProgress sets the state and the percentage of the indicator.
+func (d *tabProgressDisplay) setProgress(state, percent int) {
+	fmt.Fprintf(d.w, "\x1b]9;4;%d;%d\x1b\\", state, percent)
+}
+
+// setStatus sets the indicator to the progress of s.
+func (d *tabProgressDisplay) setStatus(s *status) {
+	state := tabProgressNormal
+	if s.paused {
+		state = tabProgressPaused
+	}
+	d.setProgress(state, newFormatData(s, "").Percent)
+}
+
+func (d *tabProgressDisplay) begin(s *status) {
+	d.display.begin(s)
+	d.setStatus(s)
+}
+
+func (d *tabProgressDisplay) update(s *status) {
+	d.display.update(s)
+	d.setStatus(s)
+}
+
+func (d *tabProgressDisplay) end(s *status) {
+	d.display.end(s)
+	// The error state makes the tab stand out while the alarm fires.
+	d.setProgress(tabProgressError, 100)
+}
+
+func (d *tabProgressDisplay) close() {
+	d.display.close()
+	if d.closed {
+		return
+	}
+	d.closed = true
+	d.setProgress(tabProgressRemove, 0)
+}
@@ -0,0 +1,46 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+)
+
+// terminalSize is not supported on this platform.
+func terminalSize(f *os.File) (cols, rows int, ok bool) {
+	return 0, 0, false
+}
+
+// isTerminal is not supported on this platform.
+func isTerminal(w io.Writer) bool {
+	return false
+}
+
+// notifyResize is not supported on this platform.
+func notifyResize(ch chan<- os.Signal) {}
+
+// notifyPause is not supported on this platform.
+func notifyPause(ch chan<- os.Signal) {}
+
+// probeMode is not supported on this platform.
+func probeMode(mode int) (supported, ok bool) {
+	return false, false
+}
+
+// readKey is not supported on this platform.
+func readKey(done <-chan struct{}) bool {
+	return false
+}
+
+// startGroup starts cmd. Process group is not supported on this platform.
+func startGroup(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+// killGroup kills cmd.
+func killGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,146 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// terminalSize returns the number of columns and rows of the terminal
+// connected to f. ok is false if f is not a terminal.
+func terminalSize(f *os.File) (cols, rows int, ok bool) {
+	var ws struct {
+		row, col, xpixel, ypixel uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.col == 0 || ws.row == 0 {
+		return 0, 0, false
+	}
+	return int(ws.col), int(ws.row), true
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	var ws [4]uint16
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	return errno == 0
+}
+
+// notifyResize relays SIGWINCH to ch.
+func notifyResize(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGWINCH)
+}
+
+// notifyPause relays SIGUSR1 which pauses and resumes the timer to ch.
+func notifyPause(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR1)
+}
+
+// probeMode asks the terminal whether DEC private mode is supported with
+// DECRQM. ok is false if the terminal doesn't answer in time.
+func probeMode(mode int) (supported, ok bool) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false, false
+	}
+	defer tty.Close()
+
+	var orig syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(ioctlGetTermios), uintptr(unsafe.Pointer(&orig))); errno != 0 {
+		return false, false
+	}
+	// Non-canonical mode without echo, waiting the reply for 0.3 seconds.
+	raw := orig
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 3
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(ioctlSetTermios), uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return false, false
+	}
+	defer syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(ioctlSetTermios), uintptr(unsafe.Pointer(&orig)))
+
+	fmt.Fprintf(tty, "\x1b[?%d$p", mode)
+	// The reply is "CSI ? mode ; Ps $ y".
+	var reply []byte
+	buf := make([]byte, 32)
+	for len(reply) < 64 {
+		n, err := tty.Read(buf)
+		if n == 0 || err != nil {
+			return false, false
+		}
+		reply = append(reply, buf[:n]...)
+		if strings.HasSuffix(string(reply), "$y") {
+			break
+		}
+	}
+	var m, ps int
+	if _, err := fmt.Sscanf(strings.TrimLeft(string(reply), "\x1b["), "?%d;%d$y", &m, &ps); err != nil || m != mode {
+		return false, false
+	}
+	// 0: not recognized, 4: permanently reset
+	return ps != 0 && ps != 4, true
+}
+
+// readKey waits for a key pressed on the controlling terminal. It returns
+// false when done is closed before that or the terminal is not available.
+func readKey(done <-chan struct{}) bool {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDONLY, 0)
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	var orig syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(ioctlGetTermios), uintptr(unsafe.Pointer(&orig))); errno != 0 {
+		return false
+	}
+	// Non-canonical mode without echo, polling done every 0.1 seconds.
+	raw := orig
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 1
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(ioctlSetTermios), uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return false
+	}
+	defer syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(ioctlSetTermios), uintptr(unsafe.Pointer(&orig)))
+
+	buf := make([]byte, 1)
+	for {
+		select {
+		case <-done:
+			return false
+		default:
+		}
+		n, err := tty.Read(buf)
+		if n > 0 {
+			return true
+		}
+		if err != nil && err != io.EOF {
+			return false
+		}
+	}
+}
+
+// startGroup starts cmd in a new process group so that killGroup kills
+// the children of the command too.
+func startGroup(cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd.Start()
+}
+
+// killGroup kills the process group started by startGroup.
+func killGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
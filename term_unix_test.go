@@ -0,0 +1,55 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCLI_pauseParallel(t *testing.T) {
+	f, err := ioutil.TempFile("", "recipe*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("steps:\n  - duration: 2\n    label: steep\n    parallel:\n      - duration: 1\n        label: stir\n")
+	f.Close()
+
+	// The step is paused for a second before the sub-timer expires.
+	time.AfterFunc(500*time.Millisecond, func() {
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	})
+	time.AfterFunc(1500*time.Millisecond, func() {
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	})
+	var out, errs lockedBuffer
+	cli := &CLI{outStream: &out, errStream: &errs}
+	start := time.Now()
+	if status := cli.Run([]string{"time-to-go", "--output", "json", "--alarm", "print", "run", f.Name()}); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errs.String())
+	}
+	fired := map[string]time.Duration{}
+	dec := json.NewDecoder(strings.NewReader(out.String()))
+	for dec.More() {
+		var ev event
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Event == "fired" {
+			fired[ev.Label] = ev.Time.Sub(start)
+		}
+	}
+	if d := fired["stir"]; d < 1900*time.Millisecond || d > 2500*time.Millisecond {
+		t.Errorf("expected %v to be delayed by the pause", d)
+	}
+	if d := fired["steep"]; d < 2900*time.Millisecond {
+		t.Errorf("expected %v to be delayed by the pause", d)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// titleDisplay updates the title of the terminal window or tab with
+// remained time in addition to the display it wraps. The previous title
+// is saved on the title stack of xterm and restored on close.
+type titleDisplay struct {
+	display
+	w      io.Writer
+	closed bool
+}
+
+// newTitleDisplay saves the current title and returns titleDisplay.
+func newTitleDisplay(d display, w io.Writer) *titleDisplay {
+	// push the icon name and the window title
+	fmt.Fprint(w, "\x1b[22;0t")
+	return &titleDisplay{display: d, w: w}
+}
+
+// setTitle sets both of the icon name and the window title with OSC 0.
+func (d *titleDisplay) setTitle(title string) {
+	title = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, title)
+	fmt.Fprint(d.w, "\x1b]0;"+title+"\x07")
+}
+
+func (d *titleDisplay) begin(s *status) {
+	d.display.begin(s)
+	d.setRemains(s)
+}
+
+func (d *titleDisplay) update(s *status) {
+	d.display.update(s)
+	d.setRemains(s)
+}
+
+// setRemains sets the title to remained time of s.
+func (d *titleDisplay) setRemains(s *status) {
+	d.setTitle(fmt.Sprintf("%s %s%s%s - time-to-go", clock(s.remains), s.step.label, s.progress(), s.pausedMark()))
+}
+
+func (d *titleDisplay) end(s *status) {
+	d.display.end(s)
+	name := s.step.label
+	if name == "" {
+		name = "Wake up"
+	}
+	d.setTitle(fmt.Sprintf("*** %s!!!! *** - time-to-go", name))
+}
+
+func (d *titleDisplay) close() {
+	d.display.close()
+	if d.closed {
+		return
+	}
+	d.closed = true
+	// pop the icon name and the window title
+	fmt.Fprint(d.w, "\x1b[23;0t")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// tmuxOption is the user option where remained time is published.
+// Add "#{@time-to-go}" to status-right to show it on the status line.
+const tmuxOption = "@time-to-go"
+
+// tmuxDisplay integrates the countdown with tmux in addition to the
+// display it wraps. It publishes remained time to tmuxOption of the
+// pane, and when the step expires it shows a message in the attached
+// clients and rings the bell to set the bell flag of the window.
+type tmuxDisplay struct {
+	display
+	w io.Writer
+	// tty is true if w is a terminal where the bell is rung.
+	tty   bool
+	pane  string
+	popup bool
+	// run executes tmux with args and waits for it. start doesn't wait.
+	run    func(args ...string) error
+	start  func(args ...string) error
+	closed bool
+}
+
+// newTmuxDisplay returns tmuxDisplay. It returns nil if time-to-go is
+// not running inside tmux.
+func newTmuxDisplay(d display, w io.Writer, tty, popup bool) *tmuxDisplay {
+	if _, ok := os.LookupEnv("TMUX"); !ok {
+		return nil
+	}
+	return &tmuxDisplay{display: d, w: w, tty: tty, pane: os.Getenv("TMUX_PANE"), popup: popup, run: runTmux, start: startTmux}
+}
+
+// runTmux executes tmux command.
+func runTmux(args ...string) error {
+	return exec.Command("tmux", args...).Run()
+}
+
+// startTmux executes tmux command in the background. The popup blocks
+// until it is closed, which must not delay the alarm.
+func startTmux(args ...string) error {
+	cmd := exec.Command("tmux", args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// target returns "-t PANE" args if the pane is known.
+func (d *tmuxDisplay) target() []string {
+	if d.pane == "" {
+		return nil
+	}
+	return []string{"-t", d.pane}
+}
+
+// publish sets value to tmuxOption. The option is of the pane so that
+// timers in other panes don't overwrite it.
+func (d *tmuxDisplay) publish(value string) {
+	args := append([]string{"set-option", "-p", "-q"}, d.target()...)
+	d.run(append(args, tmuxOption, value)...)
+}
+
+func (d *tmuxDisplay) begin(s *status) {
+	d.display.begin(s)
+	d.publish(tmuxStatus(s))
+}
+
+func (d *tmuxDisplay) update(s *status) {
+	d.display.update(s)
+	d.publish(tmuxStatus(s))
+}
+
+func (d *tmuxDisplay) end(s *status) {
+	d.display.end(s)
+	msg := "Wake up!!!!"
+	if s.step.label != "" {
+		msg = s.step.label + " is over."
+	}
+	d.publish("*** " + msg + " ***")
+	if d.popup {
+		cmd := fmt.Sprintf("printf '%%s\\n' %s; read -r _", shellQuote(msg))
+		args := append([]string{"display-popup"}, d.target()...)
+		d.start(append(args, "-T", " time-to-go ", "-w", "40", "-h", "5", "-E", cmd)...)
+	} else {
+		args := append([]string{"display-message"}, d.target()...)
+		d.run(append(args, "time-to-go: "+msg)...)
+	}
+	// The bell is not passed through to the outer terminal, nor limited
+	// by --bell-interval, so that tmux sets the bell flag of the window.
+	// It is not written to the redirected output.
+	if d.tty {
+		fmt.Fprint(d.w, "\a")
+	}
+}
+
+func (d *tmuxDisplay) close() {
+	d.display.close()
+	if d.closed {
+		return
+	}
+	d.closed = true
+	args := append([]string{"set-option", "-p", "-q", "-u"}, d.target()...)
+	d.run(append(args, tmuxOption)...)
+}
+
+// tmuxStatus formats s for the status line such as "tea 04:59".
+func tmuxStatus(s *status) string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s%s", s.step.label, clock(s.remains), s.pausedMark()))
+}
+
+// shellQuote quotes s with single quotes for sh.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTmuxDisplay(t *testing.T) {
+	var cmds, started []string
+	var out bytes.Buffer
+	d := &tmuxDisplay{
+		display: &lineDisplay{liveLine{w: ioutil.Discard, simple: true}},
+		w:       &out,
+		tty:     true,
+		pane:    "%1",
+		run: func(args ...string) error {
+			cmds = append(cmds, strings.Join(args, " "))
+			return nil
+		},
+		start: func(args ...string) error {
+			started = append(started, strings.Join(args, " "))
+			return nil
+		},
+	}
+	s := &status{step: step{duration: time.Minute, label: "tea"}, remains: 42}
+	d.update(s)
+	d.end(s)
+	d.close()
+	d.close()
+
+	expected := []string{
+		"set-option -p -q -t %1 @time-to-go tea 00:42",
+		"set-option -p -q -t %1 @time-to-go *** tea is over. ***",
+		"display-message -t %1 time-to-go: tea is over.",
+		"set-option -p -q -u -t %1 @time-to-go",
+	}
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("expected %q to eq %q", cmds, expected)
+	}
+	// The bell is written to the pane as it is.
+	if expected := "\a"; out.String() != expected {
+		t.Errorf("expected %q to eq %q", out.String(), expected)
+	}
+
+	// The popup doesn't block the alarm.
+	cmds = nil
+	d.popup = true
+	d.end(s)
+	if len(started) != 1 || !strings.HasPrefix(started[0], "display-popup -t %1 ") {
+		t.Errorf("expected %q to start the popup", started)
+	}
+	if len(cmds) != 1 {
+		t.Errorf("expected %q to only publish", cmds)
+	}
+
+	// The bell is not written to the redirected output.
+	out.Reset()
+	d.tty = false
+	d.end(s)
+	if out.Len() != 0 {
+		t.Errorf("expected %q to be empty", out.String())
+	}
+}
@@ -12,11 +12,171 @@ import (
 )
 
 var helpMessage = `Usage:
-  time-to-go <TIME>
+  time-to-go <TIME> [LABEL] [--then <TIME> [LABEL]]...
+  time-to-go <NAME=TIME>...
+  time-to-go run <RECIPE>
+  time-to-go validate <RECIPE>...
 
 Options:
   -s, --simple
         Simple output which doesn't show remained seconds.
+  -m, --message <MESSAGE>
+        Message of the notification. It may contain {{.Label}}, {{.Start}},
+        {{.End}} and {{.Duration}} which are replaced by the values of the timer.
+  --refresh <INTERVAL>
+        Interval of updating remained time. When the output is not a terminal,
+        remained time is written in a new line at this interval.
+        (default: 1s on a terminal, 1min otherwise)
+  --color <auto|always|never>
+        Color error messages. auto disables color when stderr is not a
+        terminal, NO_COLOR is set or TERM is dumb. (default: auto)
+  --output <human|json>
+        With json, events (started, tick, warning, paused, resumed, cancelled,
+        fired, alarm, snoozed) are written to stdout as JSON lines and human
+        readable output goes to stderr. warning is emitted at --thresholds and
+        alarm tells the alarms which fired.
+        (default: human)
+  --progress
+        Show progress bar with elapsed and total time, percentage and ETA.
+  --format <TEMPLATE>
+        Go text/template of the countdown, used for the banner, the live line
+        and the final message. Fields: .State (started, running, finished),
+        .Label, .Step, .Steps, .Remaining, .Elapsed, .Total, .Percent, .Start,
+        .End, .Hours, .Minutes, .Seconds. Functions: pad, rpad, clock, humanize.
+  --title
+        Show remained time in the title of the terminal window or tab.
+        The previous title is restored on exit.
+  --tab-progress
+        Show progress on the terminal tab or taskbar with OSC 9;4, which is
+        supported by ConEmu, Windows Terminal and WezTerm. It turns to the
+        error state when the alarm fires.
+  --tmux
+        Inside tmux, publish remained time to the pane option @time-to-go
+        (tmux 3.0+), which "#{@time-to-go}" in status-right shows for the
+        active pane. When the timer expires, show a message in the attached
+        clients and ring the bell to set the bell flag of the window.
+  --tmux-popup
+        Same as --tmux but show the message with display-popup (tmux 3.2+).
+  --progress-notification
+        Keep a desktop notification with remained time and a progress bar
+        while the timer runs, updated in place at most every 5 seconds. It
+        is replaced by the notification of the alarm.
+  --big
+        Full-screen output with large digits which scale with the terminal.
+  --thresholds <YELLOW,RED>
+        Remained time where --big turns yellow and red and --output json
+        emits warning, in percentage of the duration or TIME.
+        (default: 50%,20%)
+  --notifier <dbus|libnotify|terminal|osc9|osc777|osc99>
+        Notification of the alarm. dbus shows desktop notification on the
+        machine running time-to-go through the notification server on the
+        session bus, and so does libnotify when time-to-go is built with
+        -tags libnotify. The others ask the terminal to show notification
+        with escape sequences, which works over SSH: osc9 for iTerm2 and
+        WezTerm, osc777 for urxvt and foot, osc99 for kitty, and terminal
+        detects one of them. When the notification server doesn't answer on
+        startup, dbus falls back to terminal with a warning. (default: dbus)
+  --alarm <ALARM>
+        Alarm fired when the timer is over, which can be repeated or given
+        as a comma separated list: dbus, libnotify, osc (terminal
+        notification of --notifier), flash, bell, command, sound, print (a
+        line on the output) and wall (message to the terminals of all
+        users).
+        When none of them succeeds, bell and print are fired instead.
+        (default: --notifier and flash)
+  --bell
+        Ring the terminal bell in addition to the alarms. The bell passes
+        through the multiplexers like screen flashing.
+  --bell-tick <N>
+        Ring the bell every second during the final N seconds. With the
+        sound alarm, a short tick is played instead.
+  --bell-interval <TIME>
+        Minimum interval between bells, which are dropped within it not to
+        flood terminals queuing them. (default: 500ms)
+  --alarm-command <COMMAND>
+        Shell command run by the command alarm with TIME_TO_GO_SUMMARY and
+        TIME_TO_GO_BODY in the environment.
+  --sound <beep|chime|alarm|FILE>
+        Sound of the sound alarm. The embedded sounds and WAV files are
+        piped to the player in WAV, and other files as they are.
+        (default: alarm)
+  --sound-player <COMMAND>
+        Shell command which plays the sound from stdin.
+        (default: the first found of paplay, pw-play, aplay and ffplay)
+  --sound-sink <FILE>
+        File or device where the sound is written as raw PCM (signed 16-bit
+        little endian, 44.1kHz mono for the embedded sounds) instead of the
+        player, such as a FIFO read by "pacat" or /dev/null.
+  --sound-until-ack
+        Repeat the sound of the last alarm until a key is pressed or a
+        signal is received. The gap between repeats grows up to 5 seconds
+        while the player exits without playing.
+  --sound-ramp <TIME>
+        Raise the volume of the sound from a tenth to full over TIME.
+  --snooze <INTERVAL>
+        When the notification server supports actions, the notification of
+        the last alarm has Snooze, +5 min and Dismiss buttons for a minute.
+        Snooze restarts the countdown for INTERVAL and +5 min for 5 minutes.
+        (default: 10min)
+  --escalate <INTERVAL>
+        Repeat the last alarm INTERVAL after the previous one is over until
+        a key is pressed, Dismiss is clicked or a signal is received. Each
+        repeat raises the urgency and adds the next of --escalate-alarm.
+  --escalate-max <TIME>
+        How long --escalate repeats the alarm. (default: 10min)
+  --escalate-alarm <ALARM>
+        Alarms added one by one by --escalate, which can be repeated or
+        given as a comma separated list like --alarm. wall is added only
+        if it is given here. (default: bell,sound)
+  --urgency <low|normal|critical>
+        Urgency of the desktop notification. Servers usually keep critical
+        notifications until they are closed. (default: normal)
+  --expire <TIME|never|default>
+        Time until the desktop notification expires, never to keep it until
+        it is closed, or default of the server. The buttons of --snooze are
+        shown until it expires. (default: default)
+  --category <CATEGORY>
+        Category of the desktop notification such as "im" or "transfer".
+  --icon <ICON>
+        Icon name or image file shown in the desktop notification.
+  --sound-file <FILE>
+  --sound-name <NAME>
+        Sound file or themed sound name such as "alarm-clock-elapsed" played
+        by the notification server when it supports sounds.
+  --resident
+        Keep the desktop notification after a button is clicked.
+  --transient
+        Keep the desktop notification out of the history of the server.
+  --flash <blink|sos|pulse>
+        Pattern of screen flashing of the alarm. blink flashes at regular
+        intervals, sos flashes SOS in Morse code and pulse flashes faster
+        and faster. (default: blink)
+  --flash-count <COUNT>
+        Number of times the flash pattern is repeated.
+        (default: 6 for blink, 1 for sos, 2 for pulse)
+  --flash-on <INTERVAL>
+  --flash-off <INTERVAL>
+        Duration of a flash and between flashes, which is the length of a dot
+        for sos and of the first flash for pulse.
+        (default: 500ms for blink, 200ms for sos, 400ms for pulse)
+  --flash-until-ack
+        Repeat the flash pattern when the timer is over until a key is
+        pressed or a signal is received. The screen is always restored.
+  --flash-style <auto|reverse|background>
+        Style of screen flashing. reverse uses reverse video (DECSCNM) and
+        background changes the background color (OSC 11). auto asks the
+        terminal whether reverse video is supported. (default: auto)
+  --multiplexer <auto|none|MULTIPLEXERS>
+        Terminal multiplexers between time-to-go and the terminal, from the
+        innermost one such as "tmux,screen" for tmux inside GNU screen.
+        Escape sequences are wrapped to pass through them. auto detects them
+        from TMUX, STY and TERM of tmux. GNU screen over SSH needs to be
+        given since TERM=screen is used by tmux too. (default: auto)
+  --every <INTERVAL>
+        Chime at every INTERVAL (e.g. 10min, 10m) without stopping the countdown.
+  --chime <bell|flash|notify>
+        Kind of chime used by --every. Without a terminal, bell and flash
+        print a line instead. (default: bell)
   -h, --help
         Print this help message.
   -v, --version
@@ -39,14 +199,52 @@ i.e.) second: s, minute: min, hour: h
   1 hours 20 minutes 30 seconds: 1 h 20 min 30 s, 1h 20min 30s, 1 20 30, 1.20.30, 1:20:30
   2 hours 40 seconds: 2 h 40 s, 2h 40s, 2 0 45
 
-Press Ctrl+C to cancel the timer.
+To chime every 10 minutes during 40 minutes meditation.
+
+  $ time-to-go --every 10min 40min
+
+To set alarm 3 minutes with a label and a message.
+
+  $ time-to-go -m "{{.Label}} started at {{.Start}} is ready" 3min tea
+
+To show large digits turning yellow at 5 minutes and red at 1 minute left.
+
+  $ time-to-go --big --thresholds 5min,1min 15min
+
+To show remained time in your own format.
+
+  $ time-to-go --format '{{.Label}} {{clock .Remaining}} ({{.Percent}}%)' 25min pomodoro
+
+To run timers one after another with labels.
+
+  $ time-to-go 3min steep --then 10min cool --then 2min serve
+
+To flash SOS until you press a key.
+
+  $ time-to-go --flash sos --flash-until-ack 5min
+
+To run timers concurrently with their names. They are shown in a table, so
+the flags of the display such as --big and --tmux cannot be used.
+
+  $ time-to-go pasta=9min sauce=20min bread=35min
+
+To run a procedure described in YAML or TOML recipe file, and to check
+recipe files without running them.
+
+  $ time-to-go run tea.yaml
+  $ time-to-go validate tea.yaml pasta.toml
+
+Press Ctrl+C to cancel the timer. Send SIGUSR1 to pause and resume it.
 `
 
 var re = regexp.MustCompile(`:+`)
 
+// chimes are the kinds of lightweight alert available for --every.
+var chimes = []string{"bell", "flash", "notify"}
+
 // printUsage prints help message.
 func printUsage() {
-	fmt.Fprintf(os.Stderr, helpMessage)
+	fmt.Fprint(os.Stderr, helpMessage)
 	flag.PrintDefaults()
 }
 
@@ -143,25 +341,170 @@ func getDuration(args []string) (time.Duration, error) {
 	return d, err
 }
 
-// flashScreen makes current terminal screen flashing for t times
-// using ANSI escape sequences. It has the potential not to work in
-// some terminal environments.
-func flashScreen(t int) {
-	// workaround for TMUX.
-	escSeq := "\x1b[?%s"
-	_, tmux := os.LookupEnv("TMUX")
-	if tmux == true {
-		escSeq = "\x1bPtmux;\x1b\x1b[?%s\x1b\\"
-	}
-	for i := 0; i < t; i++ {
-		// reverse video
-		fmt.Fprintf(os.Stdout, escSeq, "5h")
-		time.Sleep(500 * time.Millisecond)
-		// normal video
-		fmt.Fprintf(os.Stdout, escSeq, "5l")
-		if i == t-1 {
-			break
+// step is a single timer of the chain given by --then or a recipe file.
+type step struct {
+	duration     time.Duration
+	label        string
+	instructions string
+	// confirm makes the step wait for Enter before it starts.
+	confirm bool
+	// parallel are sub-timers which run along with the step.
+	parallel []step
+	// alarms are the names of alarmBackends selected for the step.
+	alarms []string
+	// notification overrides the style of desktop notification.
+	notification notificationStyle
+}
+
+// name returns the label of the step, or "Step no" if it has no label.
+func (st step) name(no int) string {
+	if st.label != "" {
+		return st.label
+	}
+	return fmt.Sprintf("Step %d", no)
+}
+
+// suffix returns the label formatted to be appended to messages.
+func (st step) suffix() string {
+	if st.label == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", st.label)
+}
+
+// parseSteps splits args by "--then" and converts each of them to step.
+// Leading words of each step are TIME and the rest is the label.
+func parseSteps(args []string) ([]step, error) {
+	var steps []step
+	var cur []string
+	for _, a := range append(args, "--then") {
+		if a != "--then" && a != "-then" {
+			cur = append(cur, a)
+			continue
+		}
+		st, err := parseStep(cur)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, st)
+		cur = nil
+	}
+	return steps, nil
+}
+
+// parseNamedTimers converts args such as "pasta=9m" to steps which run
+// concurrently. ok is false if args are not named timers.
+func parseNamedTimers(args []string) (timers []step, ok bool, err error) {
+	if len(args) == 0 || !strings.Contains(args[0], "=") {
+		return nil, false, nil
+	}
+	for _, a := range args {
+		i := strings.Index(a, "=")
+		if i <= 0 {
+			return nil, true, fmt.Errorf("Wrong format: %s (must be NAME=TIME)", a)
+		}
+		d, err := parseInterval(a[i+1:])
+		if err != nil || d <= 0 {
+			return nil, true, fmt.Errorf("Wrong format: %s (must be NAME=TIME)", a)
+		}
+		timers = append(timers, step{duration: d, label: a[:i]})
+	}
+	return timers, true, nil
+}
+
+// parseStep converts args to step. It takes the longest leading words
+// which can be converted to a positive duration as TIME. Flags are not
+// parsed after TIME, so the label must not look like a flag.
+func parseStep(args []string) (step, error) {
+	for i := len(args); i > 0; i-- {
+		d, err := parseInterval(strings.Join(args[:i], " "))
+		if err == nil && d > 0 {
+			for _, a := range args[i:] {
+				if len(a) > 1 && strings.HasPrefix(a, "-") {
+					return step{}, fmt.Errorf("Flags must be given before TIME: %s", a)
+				}
+			}
+			return step{duration: d, label: strings.Join(args[i:], " ")}, nil
+		}
+	}
+	return step{}, errors.New("Wrong format")
+}
+
+// messageData is the values available in the template of --message.
+type messageData struct {
+	Label    string
+	Start    string
+	End      string
+	Duration string
+}
+
+// newMessageData returns messageData of st started at start.
+func newMessageData(st step, start time.Time) messageData {
+	return messageData{
+		Label:    st.label,
+		Start:    start.Format("15:04:05"),
+		End:      start.Add(st.duration).Format("15:04:05"),
+		Duration: formatDuration(st.duration),
+	}
+}
+
+// notificationSummary returns the summary of notification for st.
+// progress is appended to the application name.
+func notificationSummary(st step, progress string) string {
+	if st.label == "" {
+		return "time-to-go" + progress
+	}
+	return "time-to-go" + progress + ": " + st.label
+}
+
+// formatDuration formats d such as "3min20s".
+func formatDuration(d time.Duration) string {
+	return strings.Replace(d.String(), "m", "min", 1)
+}
+
+// formatRemains formats rem seconds right-aligned such as " 03min20s".
+func formatRemains(rem int) string {
+	min := rem / 60
+	sec := rem % 60
+	hour := min / 60
+	min = min % 60
+	switch {
+	case hour > 0:
+		return fmt.Sprintf("%02vh%02vmin%02vs", hour, min, sec)
+	case min > 0:
+		return fmt.Sprintf("   %02vmin%02vs", min, sec)
+	default:
+		return fmt.Sprintf("        %02vs", sec)
+	}
+}
+
+// clock formats sec seconds as "H:MM:SS" or "MM:SS".
+func clock(sec int) string {
+	if sec < 0 {
+		sec = 0
+	}
+	h, m, s := sec/3600, sec/60%60, sec%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// parseInterval converts s to time.Duration. It accepts Go duration
+// format such as "10m" as well as the TIME format of getDuration.
+func parseInterval(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	return getDuration(strings.Fields(s))
+}
+
+// isOneOf reports whether s is one of list.
+func isOneOf(s string, list []string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
 		}
-		time.Sleep(500 * time.Millisecond)
 	}
+	return false
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected time.Duration
+	}{
+		{"10m", 10 * time.Minute},
+		{"90s", 90 * time.Second},
+		{"10min", 10 * time.Minute},
+		{"1:30", 90 * time.Second},
+	}
+	for _, c := range cases {
+		d, err := parseInterval(c.in)
+		if err != nil {
+			t.Errorf("parseInterval(%q) returned error: %v", c.in, err)
+			continue
+		}
+		if d != c.expected {
+			t.Errorf("expected %v to eq %v", d, c.expected)
+		}
+	}
+}
+
+func TestParseSteps(t *testing.T) {
+	args := []string{"3m", "steep", "--then", "10", "min", "cool", "down", "--then", "2:00"}
+	expected := []step{
+		{duration: 3 * time.Minute, label: "steep"},
+		{duration: 10 * time.Minute, label: "cool down"},
+		{duration: 2 * time.Minute},
+	}
+	steps, err := parseSteps(args)
+	if err != nil {
+		t.Fatalf("parseSteps returned error: %v", err)
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("expected %d steps to eq %d", len(steps), len(expected))
+	}
+	for i := range expected {
+		if steps[i].duration != expected[i].duration || steps[i].label != expected[i].label {
+			t.Errorf("expected %v to eq %v", steps[i], expected[i])
+		}
+	}
+
+	if _, err := parseSteps([]string{"3m", "--then"}); err == nil {
+		t.Errorf("expected error for an empty step")
+	}
+	if _, err := parseSteps([]string{"1s", "tea", "--big"}); err == nil {
+		t.Errorf("expected error for the flag after TIME")
+	}
+}
+
+func TestParseNamedTimers(t *testing.T) {
+	timers, ok, err := parseNamedTimers([]string{"pasta=9m", "sauce=1:30"})
+	if !ok || err != nil {
+		t.Fatalf("parseNamedTimers returned %v, %v", ok, err)
+	}
+	if len(timers) != 2 || timers[0].label != "pasta" || timers[0].duration != 9*time.Minute ||
+		timers[1].label != "sauce" || timers[1].duration != 90*time.Second {
+		t.Errorf("unexpected timers %+v", timers)
+	}
+
+	if _, ok, _ := parseNamedTimers([]string{"3min", "steep"}); ok {
+		t.Errorf("expected %v not to be named timers", []string{"3min", "steep"})
+	}
+	if _, _, err := parseNamedTimers([]string{"pasta=9m", "sauce"}); err == nil {
+		t.Errorf("expected error for a timer without name")
+	}
+}
+
+func TestNewMessageData(t *testing.T) {
+	start := time.Date(2018, 1, 16, 12, 0, 0, 0, time.Local)
+	data := newMessageData(step{duration: 3*time.Minute + 20*time.Second, label: "tea"}, start)
+	expected := messageData{Label: "tea", Start: "12:00:00", End: "12:03:20", Duration: "3min20s"}
+	if data != expected {
+		t.Errorf("expected %+v to eq %+v", data, expected)
+	}
+}
+
+func TestClock(t *testing.T) {
+	for sec, expected := range map[int]string{0: "00:00", 75: "01:15", 3725: "1:02:05"} {
+		if s := clock(sec); s != expected {
+			t.Errorf("expected %q to eq %q", s, expected)
+		}
+	}
+}
+
+func TestPrintUsage(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(f *os.File) { os.Stderr = f }(os.Stderr)
+	os.Stderr = w
+	printUsage()
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The examples such as --format are printed as they are.
+	for _, s := range []string{"({{.Percent}}%)", "(default: 50%,20%)"} {
+		if !strings.Contains(string(out), s) {
+			t.Errorf("expected the usage to contain %q", s)
+		}
+	}
+	if strings.Contains(string(out), "%!") {
+		t.Errorf("expected %q not to contain %%!", out)
+	}
+}